@@ -56,8 +56,11 @@ func init() {
 }
 
 func backup(cmd *cobra.Command, args []string) {
-	urlstore.NewSession()
-	defer urlstore.CloseSession()
+	store, err := urlstore.NewStore(settings)
+	if err != nil {
+		mlog.Fatalf("Error opening the database at %s: %v", settings.Server.DbPath, err)
+	}
+	defer store.Close()
 	abp, err := filepath.Abs(backupFile)
 	if err != nil {
 		mlog.Fatalf("Invalid path %s: %v", backupFile, err)
@@ -66,7 +69,7 @@ func backup(cmd *cobra.Command, args []string) {
 	if err != nil {
 		mlog.Fatalf("Error create backup path to %s: %v", backupFile, err)
 	}
-	if err = urlstore.Backup(abp); err != nil {
+	if err = store.Backup(abp); err != nil {
 		mlog.Fatalf("Error create backup at %s: %v", backupFile, err)
 	}
 }
@@ -50,8 +50,11 @@ func init() {
 }
 
 func importCsv(cmd *cobra.Command, args []string) {
-	urlstore.NewSession()
-	defer urlstore.CloseSession()
+	store, err := urlstore.NewStore(settings)
+	if err != nil {
+		mlog.Fatalf("Error opening the database at %s: %v", settings.Server.DbPath, err)
+	}
+	defer store.Close()
 	abp, err := filepath.Abs(csvFile)
 	if err != nil {
 		mlog.Fatalf("Invalid path %s: %v", csvFile, err)
@@ -59,7 +62,7 @@ func importCsv(cmd *cobra.Command, args []string) {
 	if _, err = os.Stat(abp); os.IsNotExist(err) {
 		mlog.Fatalf("Invalid path %s: %v", csvFile, err)
 	}
-	if rows, err := urlstore.ImportCSV(abp); err != nil {
+	if rows, err := store.ImportCSV(abp); err != nil {
 		mlog.Fatalf("Error create backup at %s: %v", csvFile, err)
 	} else {
 		mlog.Info("Import complete, %d url record loaded", rows)
@@ -38,8 +38,11 @@ func init() {
 }
 
 func restore(cmd *cobra.Command, args []string) {
-	urlstore.NewSession()
-	defer urlstore.CloseSession()
+	store, err := urlstore.NewStore(settings)
+	if err != nil {
+		mlog.Fatalf("Error opening the database at %s: %v", settings.Server.DbPath, err)
+	}
+	defer store.Close()
 	abp, err := filepath.Abs(backupFile)
 	if err != nil {
 		mlog.Fatalf("Invalid path %s: %v", backupFile, err)
@@ -47,7 +50,7 @@ func restore(cmd *cobra.Command, args []string) {
 	if _, err := os.Stat(abp); os.IsNotExist(err) {
 		mlog.Fatalf("Invalid path %s: %v", csvFile, err)
 	}
-	if count, err := urlstore.Restore(abp); err != nil {
+	if count, err := store.Restore(abp); err != nil {
 		mlog.Fatalf("Error restoring backup from %s: %v", backupFile, err)
 	} else {
 		mlog.Info("Restored %d URLs from %s ", count, backupFile)
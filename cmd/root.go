@@ -31,6 +31,9 @@ import (
 var cfgFile, logFile, version string
 var profile, debug, generateConfigOnly bool
 
+// settings holds the distill configuration
+var settings urlstore.ConfigSchema
+
 // RootCmd represents the base command when called without any subcommands
 var RootCmd = &cobra.Command{
 	Use:   "distill",
@@ -96,8 +99,8 @@ func initConfig() {
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err == nil {
 		mlog.Info("Using config file: %v", viper.ConfigFileUsed())
-		viper.Unmarshal(&urlstore.Config)
-		urlstore.Config.Validate()
+		viper.Unmarshal(&settings)
+		settings.Validate()
 	} else {
 		switch err.(type) {
 		case viper.ConfigFileNotFoundError:
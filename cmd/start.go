@@ -16,9 +16,16 @@ package cmd
 // limitations under the License.
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"net/http"
+	"os"
+	"os/signal"
 	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/noandrea/distill/urlstore"
 	"github.com/noandrea/distill/web"
@@ -31,10 +38,31 @@ import (
 var startCmd = &cobra.Command{
 	Use:   "start",
 	Short: "Start distill",
-	Long:  ``,
-	Run:   start,
+	Long: `Start the distill server.
+
+On SIGINT or SIGTERM the server stops accepting connections, waits up to
+server.shutdown_timeout seconds for the pending requests, flushes the
+counters and statistics and closes the database. The requests still pending
+after the timeout are dropped and their handlers have a few more seconds
+to return before the database is closed.
+
+The exit code is 0 for a clean shutdown, 2 when the pending requests
+did not complete within the timeout and 1 for any other error.`,
+	Run: start,
 }
 
+const (
+	// exitShutdownTimeout is the exit code when the pending
+	// requests did not complete within the shutdown timeout
+	exitShutdownTimeout = 2
+	// exitShutdownError is the exit code when the store
+	// could not be closed cleanly
+	exitShutdownError = 1
+	// shutdownGrace is the time the handlers of the dropped
+	// requests have to return before the store is closed
+	shutdownGrace = 5 * time.Second
+)
+
 var restoreFile string
 
 func init() {
@@ -59,16 +87,92 @@ func start(cmd *cobra.Command, args []string) {
 	mlog.Info(" | (_| | \\__ \\ |_| | | |")
 	mlog.Info("  \\__,_|_|___/\\__|_|_|_|  v.%v", version)
 	mlog.Info("")
-	mlog.Info("Listening to %v:%v", urlstore.Config.Server.Host, urlstore.Config.Server.Port)
+	mlog.Info("Listening to %v:%v", settings.Server.Host, settings.Server.Port)
+	if len(settings.Server.MetricsKey) == 0 {
+		mlog.Warning("server.metrics_key is not set, the /metrics endpoint is disabled")
+	}
 
-	urlstore.NewSession()
+	store, err := urlstore.NewStore(settings)
+	if err != nil {
+		mlog.Fatalf("Error opening the database at %s: %v", settings.Server.DbPath, err)
+	}
 	if len(strings.TrimSpace(restoreFile)) > 0 {
-		count, err := urlstore.Restore(restoreFile)
+		count, err := store.Restore(restoreFile)
 		if err != nil {
 			mlog.Fatalf("Error restoring URLs from %s: %v ", restoreFile, err)
 		}
 		mlog.Info("Restored %d URLs from %s ", count, restoreFile)
 	}
-	r := web.RegisterEndpoints()
-	http.ListenAndServe(fmt.Sprintf("%s:%d", urlstore.Config.Server.Host, urlstore.Config.Server.Port), r)
+	requests := &requestTracker{}
+	srv := &http.Server{
+		Addr:    fmt.Sprintf("%s:%d", settings.Server.Host, settings.Server.Port),
+		Handler: requests.handler(web.RegisterEndpoints(store)),
+	}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			mlog.Fatalf("Error starting the server: %v", err)
+		}
+	}()
+	// wait for the termination signal
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	sig := <-quit
+	mlog.Info("Received %v, shutting down", sig)
+
+	timeout := time.Duration(settings.Server.ShutdownTimeout) * time.Second
+	exitCode := shutdown(srv, requests, store, timeout, shutdownGrace)
+	mlog.Info("Shutdown complete")
+	mlog.Stop()
+	os.Exit(exitCode)
+}
+
+// shutdown stops the server and closes the store, it returns the exit code.
+// The pending requests have timeout to complete, then they are dropped
+// and their handlers have grace to return before the store is closed
+func shutdown(srv *http.Server, requests *requestTracker, store io.Closer, timeout, grace time.Duration) (exitCode int) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		mlog.Warning("Pending requests not completed in %v: %v", timeout, err)
+		exitCode = exitShutdownTimeout
+		// drop the pending requests, the handlers are not
+		// interrupted so wait for them before closing the store
+		srv.Close()
+		if !requests.wait(grace) {
+			mlog.Warning("Requests still running after %v, closing the database anyway", grace)
+		}
+	}
+	// flush the counters and the statistics and close the database
+	if err := store.Close(); err != nil {
+		mlog.Warning("Error closing the database: %v", err)
+		exitCode = exitShutdownError
+	}
+	return
+}
+
+// requestTracker counts the requests being handled
+type requestTracker struct {
+	running int64
+}
+
+// handler wraps h to count its running requests
+func (rt *requestTracker) handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&rt.running, 1)
+		defer atomic.AddInt64(&rt.running, -1)
+		h.ServeHTTP(w, r)
+	})
+}
+
+// wait waits up to timeout for the running requests
+// to complete, it tells if they have completed
+func (rt *requestTracker) wait(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for atomic.LoadInt64(&rt.running) > 0 {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return true
 }
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jbrodriguez/mlog"
+)
+
+// testStore records if the requests were running when it was closed
+type testStore struct {
+	requests *requestTracker
+	closed   bool
+	running  int64
+	err      error
+}
+
+func (s *testStore) Close() error {
+	s.closed = true
+	s.running = atomic.LoadInt64(&s.requests.running)
+	return s.err
+}
+
+func TestShutdown(t *testing.T) {
+	mlog.Start(mlog.LevelInfo, "")
+	tests := []struct {
+		name        string
+		handlerTime time.Duration
+		timeout     time.Duration
+		grace       time.Duration
+		closeErr    error
+		wantCode    int
+		wantRunning int64
+	}{
+		{"clean", 0, time.Second, time.Second, nil, 0, 0},
+		{"timeout", 300 * time.Millisecond, 50 * time.Millisecond, 2 * time.Second, nil, exitShutdownTimeout, 0},
+		{"timeout after grace", 2 * time.Second, 50 * time.Millisecond, 100 * time.Millisecond, nil, exitShutdownTimeout, 1},
+		{"close error", 0, time.Second, time.Second, fmt.Errorf("close failed"), exitShutdownError, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			started, handlerTime := make(chan bool, 1), tt.handlerTime
+			requests := &requestTracker{}
+			srv := &http.Server{Handler: requests.handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				started <- true
+				time.Sleep(handlerTime)
+			}))}
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			go srv.Serve(l)
+			go http.Get("http://" + l.Addr().String())
+			<-started
+
+			store := &testStore{requests: requests, err: tt.closeErr}
+			if got := shutdown(srv, requests, store, tt.timeout, tt.grace); got != tt.wantCode {
+				t.Errorf("shutdown() = %v, want %v", got, tt.wantCode)
+			}
+			if !store.closed {
+				t.Fatal("store not closed")
+			}
+			if store.running != tt.wantRunning {
+				t.Errorf("store closed with %v running requests, want %v", store.running, tt.wantRunning)
+			}
+		})
+	}
+}
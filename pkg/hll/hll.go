@@ -0,0 +1,129 @@
+// Package hll implements a HyperLogLog sketch to estimate
+// the number of distinct elements of a set
+package hll
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+const (
+	// precision is the number of bits of the hash used for the register index
+	precision = 12
+	// registers is the number of registers of a sketch
+	registers = 1 << precision
+	// encodings of the binary format
+	encodingSparse = 1
+	encodingDense  = 2
+)
+
+// ErrInvalidSketch when the binary data is not a sketch
+var ErrInvalidSketch = fmt.Errorf("invalid hll sketch")
+
+// Sketch is a HyperLogLog sketch with 4096 registers,
+// the standard error of the estimate is ~1.6%
+type Sketch struct {
+	regs []uint8
+}
+
+// New creates an empty sketch
+func New() *Sketch {
+	return &Sketch{regs: make([]uint8, registers)}
+}
+
+// Add adds an element to the sketch, h must be a
+// uniformly distributed hash of the element
+func (s *Sketch) Add(h uint64) {
+	idx := h >> (64 - precision)
+	// the marker bit limits the rank to 64-precision+1
+	w := h<<precision | 1<<(precision-1)
+	rank := uint8(bits.LeadingZeros64(w) + 1)
+	if rank > s.regs[idx] {
+		s.regs[idx] = rank
+	}
+}
+
+// Merge adds the elements of another sketch to s
+func (s *Sketch) Merge(o *Sketch) {
+	for i, r := range o.regs {
+		if r > s.regs[i] {
+			s.regs[i] = r
+		}
+	}
+}
+
+// Estimate returns the estimated number of distinct elements
+func (s *Sketch) Estimate() uint64 {
+	sum, zeros := 0.0, 0
+	for _, r := range s.regs {
+		sum += 1 / float64(uint64(1)<<r)
+		if r == 0 {
+			zeros++
+		}
+	}
+	m := float64(registers)
+	alpha := 0.7213 / (1 + 1.079/m)
+	e := alpha * m * m / sum
+	// small range correction
+	if e <= 2.5*m && zeros > 0 {
+		e = m * math.Log(m/float64(zeros))
+	}
+	return uint64(e + 0.5)
+}
+
+// MarshalBinary encodes the sketch, sketches with few
+// registers set are encoded as a list of index/value
+func (s *Sketch) MarshalBinary() (data []byte, err error) {
+	set := 0
+	for _, r := range s.regs {
+		if r > 0 {
+			set++
+		}
+	}
+	if 3*set >= registers {
+		data = make([]byte, 1+registers)
+		data[0] = encodingDense
+		copy(data[1:], s.regs)
+		return
+	}
+	data = make([]byte, 1, 1+3*set)
+	data[0] = encodingSparse
+	for i, r := range s.regs {
+		if r > 0 {
+			data = append(data, byte(i>>8), byte(i), r)
+		}
+	}
+	return
+}
+
+// UnmarshalBinary decodes a sketch encoded with MarshalBinary
+func (s *Sketch) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		return ErrInvalidSketch
+	}
+	regs := make([]uint8, registers)
+	switch data[0] {
+	case encodingDense:
+		if len(data) != 1+registers {
+			return ErrInvalidSketch
+		}
+		copy(regs, data[1:])
+	case encodingSparse:
+		if (len(data)-1)%3 != 0 {
+			return ErrInvalidSketch
+		}
+		for i := 1; i < len(data); i += 3 {
+			idx := binary.BigEndian.Uint16(data[i:])
+			if idx >= registers {
+				return ErrInvalidSketch
+			}
+			regs[idx] = data[i+2]
+		}
+	default:
+		return ErrInvalidSketch
+	}
+	s.regs = regs
+	return nil
+}
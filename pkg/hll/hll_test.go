@@ -0,0 +1,74 @@
+package hll
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"testing"
+)
+
+// hash returns a uniform hash of an element
+func hash(i int) uint64 {
+	h := sha256.Sum256([]byte(fmt.Sprint(i)))
+	return binary.BigEndian.Uint64(h[:])
+}
+
+func TestEstimate(t *testing.T) {
+	for _, n := range []int{0, 1, 10, 100, 1000, 10000, 100000} {
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			s := New()
+			for i := 0; i < n; i++ {
+				s.Add(hash(i))
+				// duplicates are not counted
+				s.Add(hash(i))
+			}
+			got := float64(s.Estimate())
+			if math.Abs(got-float64(n)) > 0.05*float64(n) {
+				t.Errorf("Estimate() = %v, want %v", got, n)
+			}
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	a, b := New(), New()
+	for i := 0; i < 3000; i++ {
+		a.Add(hash(i))
+		b.Add(hash(i + 1500))
+	}
+	a.Merge(b)
+	if got := float64(a.Estimate()); math.Abs(got-4500) > 0.05*4500 {
+		t.Errorf("Estimate() = %v, want %v", got, 4500)
+	}
+}
+
+func TestMarshalBinary(t *testing.T) {
+	for _, n := range []int{0, 10, 100000} {
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			s := New()
+			for i := 0; i < n; i++ {
+				s.Add(hash(i))
+			}
+			data, err := s.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n == 10 && len(data) != 1+3*10 {
+				t.Errorf("sparse encoding of %v bytes", len(data))
+			}
+			got := New()
+			if err = got.UnmarshalBinary(data); err != nil {
+				t.Fatal(err)
+			}
+			if got.Estimate() != s.Estimate() {
+				t.Errorf("Estimate() = %v, want %v", got.Estimate(), s.Estimate())
+			}
+		})
+	}
+	for _, data := range [][]byte{nil, {0}, {encodingDense, 1}, {encodingSparse, 0xff, 0xff, 1}} {
+		if err := New().UnmarshalBinary(data); err != ErrInvalidSketch {
+			t.Errorf("UnmarshalBinary(%v) = %v, want %v", data, err, ErrInvalidSketch)
+		}
+	}
+}
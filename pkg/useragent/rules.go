@@ -0,0 +1,67 @@
+package useragent
+
+// rule maps a set of user agent tokens to a name,
+// a rule matches if the user agent contains any of the tokens
+type rule struct {
+	name   string
+	tokens []string
+}
+
+// the rules are evaluated in order, the first match wins,
+// the tokens are matched case insensitive
+
+var botRules = []rule{
+	{"Googlebot", []string{"googlebot", "adsbot-google", "mediapartners-google", "google-inspectiontool"}},
+	{"Bingbot", []string{"bingbot", "bingpreview", "msnbot"}},
+	{"Yahoo Slurp", []string{"slurp"}},
+	{"DuckDuckBot", []string{"duckduckbot"}},
+	{"Baiduspider", []string{"baiduspider"}},
+	{"YandexBot", []string{"yandexbot", "yandeximages"}},
+	{"Applebot", []string{"applebot"}},
+	{"Facebook", []string{"facebookexternalhit", "facebot"}},
+	{"Twitterbot", []string{"twitterbot"}},
+	{"LinkedInBot", []string{"linkedinbot"}},
+	{"Slackbot", []string{"slackbot", "slack-imgproxy"}},
+	{"Discordbot", []string{"discordbot"}},
+	{"TelegramBot", []string{"telegrambot"}},
+	{"WhatsApp", []string{"whatsapp"}},
+	{"Skype", []string{"skypeuripreview"}},
+	{"curl", []string{"curl/"}},
+	{"Wget", []string{"wget/"}},
+	{"Python", []string{"python-requests", "python-urllib", "aiohttp"}},
+	{"Go", []string{"go-http-client"}},
+	{"Java", []string{"java/", "apache-httpclient", "okhttp"}},
+	{"Headless Chrome", []string{"headlesschrome"}},
+	{"Bot", []string{"bot", "crawler", "spider", "crawling", "scraper", "preview", "monitor", "http-client", "fetcher"}},
+}
+
+var browserRules = []rule{
+	{"Edge", []string{"edg/", "edge/", "edga/", "edgios/"}},
+	{"Opera", []string{"opr/", "opera", "opios/"}},
+	{"Samsung Internet", []string{"samsungbrowser"}},
+	{"UC Browser", []string{"ucbrowser", "ucweb"}},
+	{"Yandex Browser", []string{"yabrowser"}},
+	{"Vivaldi", []string{"vivaldi"}},
+	{"Silk", []string{"silk/"}},
+	{"Firefox", []string{"firefox/", "fxios/"}},
+	{"Chrome", []string{"chrome/", "crios/", "chromium/"}},
+	{"Safari", []string{"safari/"}},
+	{"Internet Explorer", []string{"msie ", "trident/"}},
+}
+
+var osRules = []rule{
+	{"Windows Phone", []string{"windows phone"}},
+	{"Windows", []string{"windows"}},
+	{"iOS", []string{"iphone", "ipad", "ipod"}},
+	{"Android", []string{"android"}},
+	{"Chrome OS", []string{"cros "}},
+	{"macOS", []string{"macintosh", "mac os x"}},
+	{"Linux", []string{"linux", "x11"}},
+}
+
+var deviceRules = []rule{
+	{DeviceTV, []string{"smart-tv", "smarttv", "appletv", "googletv", "hbbtv", "crkey", "roku", "webos", "tizen"}},
+	{DeviceConsole, []string{"playstation", "xbox", "nintendo"}},
+	{DeviceTablet, []string{"ipad", "tablet", "kindle", "silk/", "playbook"}},
+	{DeviceMobile, []string{"mobile", "iphone", "ipod", "android", "windows phone", "opera mini", "blackberry"}},
+}
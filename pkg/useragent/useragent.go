@@ -0,0 +1,70 @@
+// Package useragent classifies http user agents using a set of bundled rules
+package useragent
+
+import (
+	"strings"
+)
+
+// Device classes
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceTV      = "tv"
+	DeviceConsole = "console"
+	DeviceBot     = "bot"
+	// Unknown is used when a property cannot be detected
+	Unknown = "unknown"
+)
+
+// UserAgent is a classified user agent
+type UserAgent struct {
+	Browser string
+	OS      string
+	Device  string
+	Bot     bool
+}
+
+// Parse classifies a user agent string, the fields that
+// cannot be detected are set to Unknown. For bots the
+// Browser is the bot name and the Device is DeviceBot
+func Parse(ua string) (u UserAgent) {
+	l := strings.ToLower(ua)
+	u = UserAgent{
+		Browser: match(l, browserRules),
+		OS:      match(l, osRules),
+		Device:  match(l, deviceRules),
+	}
+	if len(strings.TrimSpace(l)) == 0 {
+		// clients with no user agent are scripts
+		u.Bot, u.Device = true, DeviceBot
+		return
+	}
+	if bot := match(l, botRules); bot != Unknown {
+		u.Bot, u.Browser, u.Device = true, bot, DeviceBot
+		return
+	}
+	// android tablets do not declare mobile
+	if u.Device == DeviceMobile && u.OS == "Android" && !strings.Contains(l, "mobile") {
+		u.Device = DeviceTablet
+	}
+	if u.Device == Unknown {
+		switch u.OS {
+		case "Windows", "macOS", "Linux", "Chrome OS":
+			u.Device = DeviceDesktop
+		}
+	}
+	return
+}
+
+// match returns the name of the first rule matching the user agent
+func match(ua string, rules []rule) string {
+	for _, r := range rules {
+		for _, t := range r.tokens {
+			if strings.Contains(ua, t) {
+				return r.name
+			}
+		}
+	}
+	return Unknown
+}
@@ -0,0 +1,101 @@
+package useragent
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		ua   string
+		want UserAgent
+	}{
+		{
+			"chrome windows",
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/79.0.3945.88 Safari/537.36",
+			UserAgent{"Chrome", "Windows", DeviceDesktop, false},
+		},
+		{
+			"edge windows",
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/79.0.3945.74 Safari/537.36 Edg/79.0.309.43",
+			UserAgent{"Edge", "Windows", DeviceDesktop, false},
+		},
+		{
+			"safari mac",
+			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_2) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/13.0.4 Safari/605.1.15",
+			UserAgent{"Safari", "macOS", DeviceDesktop, false},
+		},
+		{
+			"firefox linux",
+			"Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:71.0) Gecko/20100101 Firefox/71.0",
+			UserAgent{"Firefox", "Linux", DeviceDesktop, false},
+		},
+		{
+			"chrome os",
+			"Mozilla/5.0 (X11; CrOS x86_64 12607.58.0) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/79.0.3945.86 Safari/537.36",
+			UserAgent{"Chrome", "Chrome OS", DeviceDesktop, false},
+		},
+		{
+			"safari iphone",
+			"Mozilla/5.0 (iPhone; CPU iPhone OS 13_3 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/13.0.4 Mobile/15E148 Safari/604.1",
+			UserAgent{"Safari", "iOS", DeviceMobile, false},
+		},
+		{
+			"chrome ipad",
+			"Mozilla/5.0 (iPad; CPU OS 13_3 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/79.0.3945.73 Mobile/15E148 Safari/604.1",
+			UserAgent{"Chrome", "iOS", DeviceTablet, false},
+		},
+		{
+			"samsung android",
+			"Mozilla/5.0 (Linux; Android 9; SAMSUNG SM-G960F) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/10.2 Chrome/71.0.3578.99 Mobile Safari/537.36",
+			UserAgent{"Samsung Internet", "Android", DeviceMobile, false},
+		},
+		{
+			"android tablet",
+			"Mozilla/5.0 (Linux; Android 9; SM-T820) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/79.0.3945.93 Safari/537.36",
+			UserAgent{"Chrome", "Android", DeviceTablet, false},
+		},
+		{
+			"internet explorer",
+			"Mozilla/5.0 (Windows NT 6.1; WOW64; Trident/7.0; rv:11.0) like Gecko",
+			UserAgent{"Internet Explorer", "Windows", DeviceDesktop, false},
+		},
+		{
+			"xbox",
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64; Xbox; Xbox One) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/70.0.3538.102 Safari/537.36 Edge/18.19041",
+			UserAgent{"Edge", "Windows", DeviceConsole, false},
+		},
+		{
+			"googlebot",
+			"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			UserAgent{"Googlebot", Unknown, DeviceBot, true},
+		},
+		{
+			"facebook",
+			"facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)",
+			UserAgent{"Facebook", Unknown, DeviceBot, true},
+		},
+		{
+			"curl",
+			"curl/7.64.1",
+			UserAgent{"curl", Unknown, DeviceBot, true},
+		},
+		{
+			"empty",
+			"",
+			UserAgent{Unknown, Unknown, DeviceBot, true},
+		},
+		{
+			"unknown",
+			"SomeClient/1.0",
+			UserAgent{Unknown, Unknown, Unknown, false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.ua); got != tt.want {
+				t.Errorf("Parse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
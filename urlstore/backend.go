@@ -0,0 +1,89 @@
+package urlstore
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// BackendBadger persist the urls on disk using badger
+	BackendBadger = "badger"
+	// BackendMemory keeps the urls in memory, data is lost on close
+	BackendMemory = "memory"
+)
+
+// ErrKeyNotFound is returned by a backend when a key does not exists
+var ErrKeyNotFound = fmt.Errorf("key not found")
+
+// ErrReadOnlyTxn is returned when writing in a read only transaction
+var ErrReadOnlyTxn = fmt.Errorf("read only transaction")
+
+// Backend is the key/value storage used by a Store
+type Backend interface {
+	// View runs fn in a read only transaction
+	View(fn func(txn Txn) error) error
+	// Update runs fn in a read/write transaction,
+	// the changes are committed only if fn returns nil
+	Update(fn func(txn Txn) error) error
+	// NewTransaction starts a transaction,
+	// the caller must call Discard when done
+	NewTransaction(update bool) Txn
+	// Backup writes a binary dump of the storage,
+	// the format depends on the backend
+	Backup(w io.Writer) error
+	// Load loads a binary dump created with Backup
+	Load(r io.Reader) error
+	// RunGC reclaims the space used by deleted keys
+	RunGC(discardRatio float64) error
+	// Size returns the size in bytes of the index (lsm) and
+	// of the values (vlog), backends that do not separate them return vlog 0
+	Size() (lsm, vlog int64)
+	// Close closes the storage
+	Close() error
+}
+
+// Txn is a transaction on a Backend
+type Txn interface {
+	// Get returns the value of a key or ErrKeyNotFound
+	Get(k []byte) (v []byte, err error)
+	// Set sets the value of a key
+	Set(k, v []byte) error
+	// Delete removes a key
+	Delete(k []byte) error
+	// Iterate returns an iterator over the keys starting with prefix,
+	// keys are sorted in lexicographic order
+	Iterate(prefix []byte) Iterator
+	// Commit commits the transaction changes
+	Commit() error
+	// Discard discards the transaction, it is safe to call it after Commit
+	Discard()
+}
+
+// Iterator iterates over the keys of a prefix
+type Iterator interface {
+	// Next moves the iterator to the next key,
+	// it must be called also before reading the first key
+	// and returns false when there are no more keys
+	Next() bool
+	// Seek moves the iterator so that the next call to Next
+	// moves to the first key greater or equal to k
+	Seek(k []byte)
+	// Key returns the current key
+	Key() []byte
+	// Value returns the value of the current key
+	Value() (v []byte, err error)
+	// Close releases the iterator
+	Close()
+}
+
+// openBackend opens the backend selected in the configuration
+func openBackend(cfg ConfigSchema) (Backend, error) {
+	switch strings.ToLower(cfg.Server.DbBackend) {
+	case BackendBadger, "":
+		return NewBadgerBackend(cfg.Server.DbPath, cfg.Tuning.BckCSVIterPrefetchSize)
+	case BackendMemory:
+		return NewMemoryBackend(), nil
+	}
+	return nil, fmt.Errorf("unknown db backend %v", cfg.Server.DbBackend)
+}
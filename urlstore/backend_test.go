@@ -0,0 +1,241 @@
+package urlstore
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testBackends are the backends that must pass the conformance tests
+var testBackends = map[string]func(t testing.TB) Backend{
+	BackendBadger: func(t testing.TB) Backend {
+		path, _ := ioutil.TempDir("/tmp/", "distill")
+		b, err := NewBadgerBackend(path, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	},
+	BackendMemory: func(t testing.TB) Backend {
+		return NewMemoryBackend()
+	},
+}
+
+// openBackendStore opens a store with the backend named backend
+func openBackendStore(t testing.TB, backend string, cfg ConfigSchema) *Store {
+	s, err := NewStoreWithBackend(cfg, testBackends[backend](t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestBackendConformance(t *testing.T) {
+	t.Parallel()
+	for name := range testBackends {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			t.Run("txn", func(t *testing.T) { testBackendTxn(t, testBackends[name](t)) })
+			t.Run("upsert", func(t *testing.T) { testBackendUpsertURL(t, name) })
+			t.Run("redirect", func(t *testing.T) { testBackendGetURLRedirect(t, name) })
+			t.Run("delete", func(t *testing.T) { testBackendDeleteURL(t, name) })
+			t.Run("iterator", func(t *testing.T) { testBackendURLIterator(t, name) })
+			t.Run("backup", func(t *testing.T) { testBackendBackup(t, name) })
+		})
+	}
+}
+
+func testBackendTxn(t *testing.T, db Backend) {
+	defer db.Close()
+	// set and get
+	err := db.Update(func(txn Txn) error {
+		for _, k := range []string{"b2", "a1", "b1", "c1", "b3"} {
+			if err := txn.Set([]byte(k), []byte("v"+k)); err != nil {
+				return err
+			}
+		}
+		// read your own writes
+		v, err := txn.Get([]byte("a1"))
+		require.Equal(t, []byte("va1"), v)
+		return err
+	})
+	require.NoError(t, err)
+	// failed updates are not committed
+	err = db.Update(func(txn Txn) error {
+		txn.Set([]byte("b4"), []byte("vb4"))
+		txn.Delete([]byte("b1"))
+		return fmt.Errorf("rollback")
+	})
+	require.Error(t, err)
+	// delete
+	err = db.Update(func(txn Txn) error {
+		return txn.Delete([]byte("c1"))
+	})
+	require.NoError(t, err)
+	err = db.View(func(txn Txn) error {
+		_, err := txn.Get([]byte("c1"))
+		require.Equal(t, ErrKeyNotFound, err)
+		_, err = txn.Get([]byte("b4"))
+		require.Equal(t, ErrKeyNotFound, err)
+		require.Equal(t, ErrReadOnlyTxn, txn.Set([]byte("x"), []byte("x")))
+		// iterate over a prefix in order
+		keys := []string{}
+		it := txn.Iterate([]byte("b"))
+		defer it.Close()
+		for it.Next() {
+			v, err := it.Value()
+			require.NoError(t, err)
+			require.Equal(t, "v"+string(it.Key()), string(v))
+			keys = append(keys, string(it.Key()))
+		}
+		require.Equal(t, []string{"b1", "b2", "b3"}, keys)
+		// seek within the prefix
+		it.Seek([]byte("b15"))
+		require.True(t, it.Next())
+		require.Equal(t, "b2", string(it.Key()))
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func testBackendUpsertURL(t *testing.T, backend string) {
+	s := openBackendStore(t, backend, buildConifgTest())
+	defer s.Close()
+	id, err := s.UpsertURL(&URLReq{URL: "https://ilij.li", ID: "upsert", MaxRequests: 2}, false, false, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, "upsert", id)
+	_, err = s.UpsertURL(&URLReq{URL: "https://wikipedia.li", ID: "upsert"}, false, false, time.Now())
+	require.NoError(t, err)
+	u, err := s.GetURLInfo(id)
+	require.NoError(t, err)
+	require.Equal(t, "https://wikipedia.li", u.URL)
+	require.Equal(t, uint64(0), u.MaxRequests)
+	// the cached copy is replaced too
+	got, err := s.GetURLRedirect(id)
+	require.NoError(t, err)
+	require.Equal(t, "https://wikipedia.li", got)
+	_, err = s.UpsertURL(&URLReq{URL: "https://ilij.li/new", ID: "upsert"}, false, false, time.Now())
+	require.NoError(t, err)
+	got, err = s.GetURLRedirect(id)
+	require.NoError(t, err)
+	require.Equal(t, "https://ilij.li/new", got)
+	// and the flush does not write the previous one back
+	require.NoError(t, s.Flush())
+	s.uc.Purge()
+	u, err = s.Peek(id)
+	require.NoError(t, err)
+	require.Equal(t, "https://ilij.li/new", u.URL)
+	require.Equal(t, uint64(1), u.Counter)
+	// generated ids
+	id, err = s.UpsertURLSimple(&URLReq{URL: "https://ilij.li"})
+	require.NoError(t, err)
+	require.Len(t, id, s.Config.ShortID.Length)
+	require.Equal(t, uint64(4), s.GetStats().Upserts)
+}
+
+func testBackendGetURLRedirect(t *testing.T, backend string) {
+	s := openBackendStore(t, backend, buildConifgTest())
+	defer s.Close()
+	id, _ := s.UpsertURLSimple(&URLReq{URL: "https://ilij.li/ok", MaxRequests: 2, ExhaustedURL: "https://ilij.li/exhausted"})
+	for _, want := range []string{"https://ilij.li/ok", "https://ilij.li/ok"} {
+		got, err := s.GetURLRedirect(id)
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+	}
+	got, err := s.GetURLRedirect(id)
+	require.Equal(t, ErrURLExhausted, err)
+	require.Equal(t, "https://ilij.li/exhausted", got)
+	// expired
+	id, _ = s.UpsertURL(&URLReq{ID: "expired", URL: "https://ilij.li/ok", ExpiredURL: "https://ilij.li/expired", TTL: 1}, false, false, time.Now().Add(-time.Hour))
+	got, err = s.GetURLRedirect(id)
+	require.Equal(t, ErrURLExpired, err)
+	require.Equal(t, "https://ilij.li/expired", got)
+	// not found
+	_, err = s.GetURLRedirect("notfound")
+	require.Error(t, err)
+}
+
+func testBackendDeleteURL(t *testing.T, backend string) {
+	s := openBackendStore(t, backend, buildConifgTest())
+	defer s.Close()
+	// not cached
+	id, err := s.UpsertURLSimple(&URLReq{URL: "https://ilij.li/a"})
+	require.NoError(t, err)
+	require.NoError(t, s.DeleteURL(id))
+	_, err = s.Peek(id)
+	require.Error(t, err)
+	// cached by a redirect
+	id, err = s.UpsertURLSimple(&URLReq{URL: "https://ilij.li/b"})
+	require.NoError(t, err)
+	_, err = s.GetURLRedirect(id)
+	require.NoError(t, err)
+	require.True(t, s.uc.Has(id))
+	require.NoError(t, s.DeleteURL(id))
+	require.False(t, s.uc.Has(id))
+	_, err = s.Peek(id)
+	require.Error(t, err)
+	// the pending counters are not written back
+	require.NoError(t, s.Flush())
+	_, err = s.Peek(id)
+	require.Error(t, err)
+	require.Equal(t, uint64(0), s.GetStats().Urls)
+	// a cached url removed from the store is not written back
+	id, err = s.UpsertURLSimple(&URLReq{URL: "https://ilij.li/c"})
+	require.NoError(t, err)
+	_, err = s.GetURLRedirect(id)
+	require.NoError(t, err)
+	err = s.db.Update(func(txn Txn) error {
+		k, _ := keyURL(id)
+		return txn.Delete(k)
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.Flush())
+	s.uc.Purge()
+	_, err = s.Peek(id)
+	require.Error(t, err)
+}
+
+func testBackendURLIterator(t *testing.T, backend string) {
+	s := openBackendStore(t, backend, buildConifgTest())
+	defer s.Close()
+	want := map[string]bool{}
+	for i := 0; i < 137; i++ {
+		id, err := s.UpsertURLSimple(&URLReq{URL: fmt.Sprintf("http://ex.com/v=%d", i)})
+		require.NoError(t, err)
+		want[id] = true
+	}
+	got := map[string]bool{}
+	it := s.NewURLIterator()
+	for it.HasNext() {
+		u, err := it.NextURL()
+		require.NoError(t, err)
+		got[u.ID] = true
+	}
+	it.Close()
+	require.Equal(t, want, got)
+}
+
+func testBackendBackup(t *testing.T, backend string) {
+	s := openBackendStore(t, backend, buildConifgTest())
+	defer s.Close()
+	for i := 0; i < 50; i++ {
+		s.UpsertURLSimple(&URLReq{URL: fmt.Sprintf("http://ex.com/v=%d", i)})
+	}
+	var buf bytes.Buffer
+	require.NoError(t, s.db.Backup(&buf))
+	r := openBackendStore(t, backend, buildConifgTest())
+	defer r.Close()
+	require.NoError(t, r.db.Load(&buf))
+	count := 0
+	it := r.NewURLIterator()
+	for it.HasNext() {
+		count++
+	}
+	it.Close()
+	require.Equal(t, 50, count)
+}
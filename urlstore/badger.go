@@ -0,0 +1,156 @@
+package urlstore
+
+import (
+	"io"
+	"os"
+
+	"github.com/dgraph-io/badger"
+)
+
+// badgerBackend is a Backend persisted with badger
+type badgerBackend struct {
+	db           *badger.DB
+	prefetchSize int
+}
+
+// NewBadgerBackend opens (or creates) a badger database in path
+func NewBadgerBackend(path string, prefetchSize int) (b Backend, err error) {
+	opts := badger.DefaultOptions(path)
+	opts.SyncWrites = true
+	if err = os.MkdirAll(path, os.ModePerm); err != nil {
+		return
+	}
+	opts.ValueDir = path
+	db, err := badger.Open(opts)
+	if err != nil {
+		return
+	}
+	b = &badgerBackend{db: db, prefetchSize: prefetchSize}
+	return
+}
+
+func (b *badgerBackend) View(fn func(txn Txn) error) error {
+	return b.db.View(func(txn *badger.Txn) error {
+		return fn(&badgerTxn{txn: txn, prefetchSize: b.prefetchSize})
+	})
+}
+
+func (b *badgerBackend) Update(fn func(txn Txn) error) error {
+	return b.db.Update(func(txn *badger.Txn) error {
+		return fn(&badgerTxn{txn: txn, prefetchSize: b.prefetchSize})
+	})
+}
+
+func (b *badgerBackend) NewTransaction(update bool) Txn {
+	return &badgerTxn{txn: b.db.NewTransaction(update), prefetchSize: b.prefetchSize}
+}
+
+func (b *badgerBackend) Backup(w io.Writer) (err error) {
+	_, err = b.db.Backup(w, 0)
+	return
+}
+
+func (b *badgerBackend) Load(r io.Reader) error {
+	return b.db.Load(r, 16)
+}
+
+func (b *badgerBackend) RunGC(discardRatio float64) (err error) {
+	err = b.db.RunValueLogGC(discardRatio)
+	if err == badger.ErrNoRewrite {
+		err = nil
+	}
+	return
+}
+
+func (b *badgerBackend) Size() (lsm, vlog int64) {
+	return b.db.Size()
+}
+
+func (b *badgerBackend) Close() error {
+	return b.db.Close()
+}
+
+// badgerTxn wraps a badger transaction
+type badgerTxn struct {
+	txn          *badger.Txn
+	prefetchSize int
+}
+
+func (t *badgerTxn) Get(k []byte) (v []byte, err error) {
+	item, err := t.txn.Get(k)
+	if err == badger.ErrKeyNotFound {
+		return nil, ErrKeyNotFound
+	}
+	if err != nil {
+		return
+	}
+	return item.ValueCopy(nil)
+}
+
+func (t *badgerTxn) Set(k, v []byte) (err error) {
+	err = t.txn.Set(k, v)
+	if err == badger.ErrReadOnlyTxn {
+		err = ErrReadOnlyTxn
+	}
+	return
+}
+
+func (t *badgerTxn) Delete(k []byte) (err error) {
+	err = t.txn.Delete(k)
+	if err == badger.ErrReadOnlyTxn {
+		err = ErrReadOnlyTxn
+	}
+	return
+}
+
+func (t *badgerTxn) Iterate(prefix []byte) Iterator {
+	opts := badger.DefaultIteratorOptions
+	opts.Prefix = prefix
+	if t.prefetchSize > 0 {
+		opts.PrefetchSize = t.prefetchSize
+	}
+	return &badgerIterator{it: t.txn.NewIterator(opts), prefix: prefix, seek: prefix}
+}
+
+func (t *badgerTxn) Commit() error {
+	return t.txn.Commit()
+}
+
+func (t *badgerTxn) Discard() {
+	t.txn.Discard()
+}
+
+// badgerIterator wraps a badger iterator
+type badgerIterator struct {
+	it      *badger.Iterator
+	prefix  []byte
+	seek    []byte
+	started bool
+}
+
+func (i *badgerIterator) Next() bool {
+	if i.started {
+		i.it.Next()
+	} else {
+		i.it.Seek(i.seek)
+		i.started = true
+	}
+	return i.it.ValidForPrefix(i.prefix)
+}
+
+func (i *badgerIterator) Seek(k []byte) {
+	i.seek = k
+	i.started = false
+}
+
+func (i *badgerIterator) Key() []byte {
+	return i.it.Item().KeyCopy(nil)
+}
+
+func (i *badgerIterator) Value() ([]byte, error) {
+	return i.it.Item().ValueCopy(nil)
+}
+
+func (i *badgerIterator) Close() {
+	i.it.Close()
+}
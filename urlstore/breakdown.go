@@ -0,0 +1,244 @@
+package urlstore
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/noandrea/distill/pkg/useragent"
+)
+
+const (
+	// breakdownMaxValueLength is the max length of a stored value
+	breakdownMaxValueLength = 128
+	// values of the breakdown when the dimension is missing
+	breakdownDirect  = "direct"
+	breakdownUnknown = useragent.Unknown
+	breakdownIsBot   = "bot"
+	breakdownIsHuman = "human"
+)
+
+// breakdownDimensions are the dimensions of the breakdown,
+// the position is stored in the keys so new ones must be appended
+var breakdownDimensions = []string{
+	BreakdownReferrer,
+	BreakdownBrowser,
+	BreakdownOS,
+	BreakdownDevice,
+	BreakdownBot,
+	BreakdownLanguage,
+	BreakdownCountry,
+	BreakdownCity,
+	BreakdownVariant,
+}
+
+// breakdownVariantDim is the position of the variant dimension,
+// the variants are counted on redirect and not with the visit
+var breakdownVariantDim = byte(len(breakdownDimensions) - 1)
+
+// breakdownKey identifies the value of a dimension of an url
+type breakdownKey struct {
+	id    string
+	dim   byte
+	value string
+}
+
+// keyBreakdown returns the key of the value of a dimension of an id
+func keyBreakdown(id string, dim byte, value string) []byte {
+	return append(append(keyClicks(clicksBreakdown, id), dim), value...)
+}
+
+// recordVisit counts the visit in the breakdown of the url id,
+// the counters are written to the storage on flush
+func (s *Store) recordVisit(id string, v *Visit) {
+	ua := useragent.Parse(v.UserAgent)
+	bot := breakdownIsHuman
+	if ua.Bot {
+		bot = breakdownIsBot
+	}
+	values := []string{
+		referrerHost(v.Referrer),
+		ua.Browser,
+		ua.OS,
+		ua.Device,
+		bot,
+		primaryLanguage(v.AcceptLanguage),
+	}
+	if s.geo != nil {
+		country, city := s.locate(v)
+		values = append(values, country)
+		if s.Config.Stats.GeoCity {
+			values = append(values, city)
+		}
+	}
+	s.clicksM.Lock()
+	for dim, value := range values {
+		s.breakdown[breakdownKey{id: id, dim: byte(dim), value: breakdownValue(value)}]++
+	}
+	s.clicksM.Unlock()
+}
+
+// recordVariant counts a redirect to the variant target of the url id
+func (s *Store) recordVariant(id, target string) {
+	s.clicksM.Lock()
+	s.breakdown[breakdownKey{id: id, dim: breakdownVariantDim, value: breakdownValue(target)}]++
+	s.clicksM.Unlock()
+}
+
+// flushBreakdown adds the pending breakdown counters to the storage,
+// the pending counters are removed only once they are committed
+func (s *Store) flushBreakdown() (err error) {
+	s.clicksM.Lock()
+	keys, counts := make([]breakdownKey, 0, len(s.breakdown)), make([]uint64, 0, len(s.breakdown))
+	for bk, n := range s.breakdown {
+		keys, counts = append(keys, bk), append(counts, n)
+	}
+	s.clicksM.Unlock()
+	write := func(txn Txn, live func(string) bool, i int) error {
+		if !live(keys[i].id) {
+			return nil
+		}
+		k := keyBreakdown(keys[i].id, keys[i].dim, keys[i].value)
+		return txn.Set(k, itoa(dbGetUint64(txn, k)+counts[i]))
+	}
+	return s.flushBatches(len(keys), write, func(from, to int) {
+		// keep the counters increased in the meantime
+		s.clicksM.Lock()
+		for i := from; i < to; i++ {
+			if c := s.breakdown[keys[i]]; c > counts[i] {
+				s.breakdown[keys[i]] = c - counts[i]
+			} else {
+				delete(s.breakdown, keys[i])
+			}
+		}
+		s.clicksM.Unlock()
+	})
+}
+
+// Breakdown returns, for each dimension, the top values
+// by number of clicks of the url id
+func (s *Store) Breakdown(id string, top int) (b Breakdown, err error) {
+	if _, err = s.Peek(id); err != nil {
+		return
+	}
+	counts := make([]map[string]uint64, len(breakdownDimensions))
+	for i := range counts {
+		counts[i] = make(map[string]uint64)
+	}
+	prefix := keyClicks(clicksBreakdown, id)
+	err = s.db.View(func(txn Txn) (err error) {
+		it := txn.Iterate(prefix)
+		defer it.Close()
+		for it.Next() {
+			k := it.Key()[len(prefix):]
+			if len(k) == 0 || int(k[0]) >= len(counts) {
+				continue
+			}
+			v, err := it.Value()
+			if err != nil {
+				return err
+			}
+			counts[k[0]][string(k[1:])] += atoi(v)
+		}
+		return
+	})
+	if err != nil {
+		return
+	}
+	// the counters not yet flushed
+	s.clicksM.Lock()
+	for bk, n := range s.breakdown {
+		if bk.id == id {
+			counts[bk.dim][bk.value] += n
+		}
+	}
+	s.clicksM.Unlock()
+
+	b = make(Breakdown)
+	for dim, values := range counts {
+		if !s.breakdownEnabled(breakdownDimensions[dim]) {
+			continue
+		}
+		// only the urls with variants have the variant dimension
+		if breakdownDimensions[dim] == BreakdownVariant && len(values) == 0 {
+			continue
+		}
+		entries := []BreakdownEntry{}
+		for v, n := range values {
+			entries = append(entries, BreakdownEntry{Value: v, Clicks: n})
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			if entries[i].Clicks != entries[j].Clicks {
+				return entries[i].Clicks > entries[j].Clicks
+			}
+			return entries[i].Value < entries[j].Value
+		})
+		if top > 0 && len(entries) > top {
+			entries = entries[:top]
+		}
+		b[breakdownDimensions[dim]] = entries
+	}
+	return
+}
+
+// VariantClicks returns the number of redirects to each
+// variant of the url id, nil if the url has no variants
+func (s *Store) VariantClicks(id string) (vs []VariantStats, err error) {
+	u, err := s.Peek(id)
+	if err != nil || len(u.Variants) == 0 {
+		return
+	}
+	b, err := s.Breakdown(id, 0)
+	if err != nil {
+		return
+	}
+	clicks := make(map[string]uint64)
+	for _, e := range b[BreakdownVariant] {
+		clicks[e.Value] = e.Clicks
+	}
+	for _, v := range u.Variants {
+		vs = append(vs, VariantStats{URL: v.URL, Weight: v.Weight, Clicks: clicks[breakdownValue(v.URL)]})
+	}
+	return
+}
+
+// breakdownEnabled tells if a dimension is recorded with the current configuration
+func (s *Store) breakdownEnabled(dimension string) bool {
+	switch dimension {
+	case BreakdownCountry:
+		return s.geo != nil
+	case BreakdownCity:
+		return s.geo != nil && s.Config.Stats.GeoCity
+	}
+	return true
+}
+
+// breakdownValue truncates a value to the max length stored
+func breakdownValue(value string) string {
+	if len(value) > breakdownMaxValueLength {
+		return value[:breakdownMaxValueLength]
+	}
+	return value
+}
+
+// referrerHost returns the host of the referrer url
+func referrerHost(referrer string) string {
+	if len(strings.TrimSpace(referrer)) == 0 {
+		return breakdownDirect
+	}
+	u, err := url.Parse(referrer)
+	if err != nil || len(u.Hostname()) == 0 {
+		return breakdownUnknown
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// primaryLanguage returns the primary subtag of the language
+// with the highest quality in an Accept-Language header
+func primaryLanguage(acceptLanguage string) string {
+	tags := acceptedLanguages(acceptLanguage)
+	if len(tags) == 0 {
+		return breakdownUnknown
+	}
+	return strings.ToLower(strings.SplitN(tags[0], "-", 2)[0])
+}
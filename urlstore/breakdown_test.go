@@ -0,0 +1,71 @@
+package urlstore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBreakdown(t *testing.T) {
+	t.Parallel()
+	s := openBackendStore(t, BackendMemory, buildConifgTest())
+	defer s.Close()
+	id, err := s.UpsertURLSimple(&URLReq{URL: "https://ilij.li"})
+	require.NoError(t, err)
+	chrome := "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/79.0.3945.88 Safari/537.36"
+	iphone := "Mozilla/5.0 (iPhone; CPU iPhone OS 13_3 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/13.0.4 Mobile/15E148 Safari/604.1"
+	visits := []*Visit{
+		{Referrer: "https://www.Google.com/search?q=distill", UserAgent: chrome, AcceptLanguage: "it-IT,it;q=0.9,en;q=0.8"},
+		{Referrer: "https://t.co/abc", UserAgent: iphone, AcceptLanguage: "en-US"},
+		{Referrer: "https://www.google.com/", UserAgent: chrome, AcceptLanguage: "de;q=0.5,fr-CH"},
+	}
+	for _, v := range visits[:2] {
+		_, err = s.GetURLRedirectVisit(id, v)
+		require.NoError(t, err)
+	}
+	// the breakdown is merged with the flushed one
+	require.NoError(t, s.Flush())
+	_, err = s.GetURLRedirectVisit(id, visits[2])
+	require.NoError(t, err)
+	_, err = s.GetURLRedirectVisit(id, &Visit{UserAgent: "curl/7.64.1"})
+	require.NoError(t, err)
+	// not counted
+	_, err = s.GetURLRedirect(id)
+	require.NoError(t, err)
+
+	b, err := s.Breakdown(id, 2)
+	require.NoError(t, err)
+	require.Equal(t, Breakdown{
+		BreakdownReferrer: {{"www.google.com", 2}, {"direct", 1}},
+		BreakdownBrowser:  {{"Chrome", 2}, {"Safari", 1}},
+		BreakdownOS:       {{"Windows", 2}, {"iOS", 1}},
+		BreakdownDevice:   {{"desktop", 2}, {"bot", 1}},
+		BreakdownBot:      {{"human", 3}, {"bot", 1}},
+		BreakdownLanguage: {{"en", 1}, {"fr", 1}},
+	}, b)
+
+	_, err = s.Breakdown("notfound", 2)
+	require.Equal(t, ErrKeyNotFound, err)
+}
+
+func Test_primaryLanguage(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		want           string
+	}{
+		{"empty", "", "unknown"},
+		{"any", "*", "unknown"},
+		{"single", "en-US", "en"},
+		{"first", "it-IT,it;q=0.9,en;q=0.8", "it"},
+		{"quality", "de;q=0.5, FR-ch", "fr"},
+		{"not acceptable", "en;q=0", "unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := primaryLanguage(tt.acceptLanguage); got != tt.want {
+				t.Errorf("primaryLanguage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,352 @@
+package urlstore
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jbrodriguez/mlog"
+)
+
+const (
+	// clicks key markers
+	clicksHour      = 'h'
+	clicksDay       = 'd'
+	clicksBreakdown = 'b'
+	clicksUniques   = 'u'
+	// clicksCompactInterval is how often the old hourly clicks are rolled up
+	clicksCompactInterval = time.Hour
+	// clicksCompactBatch is the number of buckets compacted in a transaction
+	clicksCompactBatch = 1000
+	// clicksFlushBatch is the number of pending counters flushed in a transaction
+	clicksFlushBatch = 1000
+)
+
+// ErrInvalidGranularity when the granularity of a series is not hour or day
+var ErrInvalidGranularity = fmt.Errorf("granularity must be %s or %s", GranularityHour, GranularityDay)
+
+// clickKey identifies the hourly bucket of an url
+type clickKey struct {
+	id   string
+	hour int64
+}
+
+// keyClicks returns the key prefix of the clicks of an id of a kind,
+// the id is length prefixed so that it is not a prefix of other ids
+func keyClicks(kind byte, id string) (k []byte) {
+	k = make([]byte, 2+binary.MaxVarintLen64+len(id))
+	k[0], k[1] = keyClicksPrefix, kind
+	n := binary.PutUvarint(k[2:], uint64(len(id)))
+	n += copy(k[2+n:], id)
+	return k[:2+n]
+}
+
+// keyClick returns the key of the bucket starting at ts
+func keyClick(gran byte, id string, ts int64) []byte {
+	return append(keyClicks(gran, id), itoa(uint64(ts))...)
+}
+
+// parseKeyClick returns the id and the bucket start of a clicks key
+func parseKeyClick(k []byte) (id string, ts int64, err error) {
+	if len(k) < 2 || k[0] != keyClicksPrefix {
+		err = fmt.Errorf("invalid clicks key %X", k)
+		return
+	}
+	l, n := binary.Uvarint(k[2:])
+	if n <= 0 || len(k) != 2+n+int(l)+8 {
+		err = fmt.Errorf("invalid clicks key %X", k)
+		return
+	}
+	id = string(k[2+n : 2+n+int(l)])
+	ts = int64(atoi(k[2+n+int(l):]))
+	return
+}
+
+// startOfHour returns the start of the hourly bucket of t
+func startOfHour(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Hour)
+}
+
+// startOfDay returns the start of the daily bucket of t
+func startOfDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// recordClick counts a click for the url id at the time t,
+// the clicks are written to the storage on flush
+func (s *Store) recordClick(id string, t time.Time) {
+	s.clicksM.Lock()
+	s.clicks[clickKey{id: id, hour: startOfHour(t).Unix()}]++
+	s.clicksM.Unlock()
+}
+
+// flushClicks adds the pending clicks to the hourly buckets,
+// the pending clicks are removed only once they are committed
+func (s *Store) flushClicks() (err error) {
+	s.clicksM.Lock()
+	keys, counts := make([]clickKey, 0, len(s.clicks)), make([]uint64, 0, len(s.clicks))
+	for ck, n := range s.clicks {
+		keys, counts = append(keys, ck), append(counts, n)
+	}
+	s.clicksM.Unlock()
+	write := func(txn Txn, live func(string) bool, i int) error {
+		if !live(keys[i].id) {
+			return nil
+		}
+		k := keyClick(clicksHour, keys[i].id, keys[i].hour)
+		return txn.Set(k, itoa(dbGetUint64(txn, k)+counts[i]))
+	}
+	return s.flushBatches(len(keys), write, func(from, to int) {
+		// keep the clicks received in the meantime
+		s.clicksM.Lock()
+		for i := from; i < to; i++ {
+			if c := s.clicks[keys[i]]; c > counts[i] {
+				s.clicks[keys[i]] = c - counts[i]
+			} else {
+				delete(s.clicks, keys[i])
+			}
+		}
+		s.clicksM.Unlock()
+	})
+}
+
+// flushBatches writes n pending items, clicksFlushBatch in each transaction
+// so a large backlog does not exceed the size of a transaction.
+// The writes get the index of the item and a function telling if an url
+// is still stored, committed gets the range of items of each committed batch
+func (s *Store) flushBatches(n int, write func(txn Txn, live func(string) bool, i int) error, committed func(from, to int)) (err error) {
+	for start := 0; start < n; start += clicksFlushBatch {
+		end := start + clicksFlushBatch
+		if end > n {
+			end = n
+		}
+		err = s.db.Update(func(txn Txn) (err error) {
+			live := liveIDs(txn)
+			for i := start; i < end; i++ {
+				if err = write(txn, live, i); err != nil {
+					return
+				}
+			}
+			return
+		})
+		if err != nil {
+			return
+		}
+		committed(start, end)
+	}
+	return
+}
+
+// liveIDs returns a function that tells if an url is still stored, live,
+// archived or in the trash, the clicks of the deleted urls are not flushed
+func liveIDs(txn Txn) func(id string) bool {
+	live := make(map[string]bool)
+	return func(id string) bool {
+		l, found := live[id]
+		if !found {
+			l = idTaken(txn, id)
+			live[id] = l
+		}
+		return l
+	}
+}
+
+// dropClicks removes the pending clicks, breakdown and unique visitors of an url
+func (s *Store) dropClicks(id string) {
+	s.clicksM.Lock()
+	for ck := range s.clicks {
+		if ck.id == id {
+			delete(s.clicks, ck)
+		}
+	}
+	for bk := range s.breakdown {
+		if bk.id == id {
+			delete(s.breakdown, bk)
+		}
+	}
+	delete(s.uniques, id)
+	s.clicksM.Unlock()
+}
+
+// dbDelClicks deletes the time series, the breakdown
+// and the unique visitors of an url
+func dbDelClicks(txn Txn, id string) (err error) {
+	for _, kind := range []byte{clicksHour, clicksDay, clicksBreakdown, clicksUniques} {
+		it := txn.Iterate(keyClicks(kind, id))
+		keys := [][]byte{}
+		for it.Next() {
+			keys = append(keys, it.Key())
+		}
+		it.Close()
+		if err = dbDel(txn, keys...); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// ClickSeries returns the clicks of the url id with the start of
+// the bucket in the range [from, to), with granularity hour or day.
+// The hourly clicks are available only for the stats.hourly_retention days
+func (s *Store) ClickSeries(id, granularity string, from, to time.Time) (cs *ClickSeries, err error) {
+	bucket := startOfHour
+	switch granularity {
+	case GranularityHour:
+	case GranularityDay:
+		bucket = startOfDay
+	default:
+		return nil, ErrInvalidGranularity
+	}
+	if _, err = s.Peek(id); err != nil {
+		return
+	}
+	counts := make(map[int64]uint64)
+	add := func(ts int64, n uint64) {
+		t := time.Unix(ts, 0)
+		if t.Before(bucket(from)) || !t.Before(to) {
+			return
+		}
+		counts[bucket(t).Unix()] += n
+	}
+	grans := []byte{clicksHour}
+	if granularity == GranularityDay {
+		grans = append(grans, clicksDay)
+	}
+	err = s.db.View(func(txn Txn) (err error) {
+		for _, gran := range grans {
+			it := txn.Iterate(keyClicks(gran, id))
+			for it.Next() {
+				_, ts, err := parseKeyClick(it.Key())
+				if err != nil {
+					continue
+				}
+				v, err := it.Value()
+				if err != nil {
+					it.Close()
+					return err
+				}
+				add(ts, atoi(v))
+			}
+			it.Close()
+		}
+		return
+	})
+	if err != nil {
+		return
+	}
+	// the clicks not yet flushed
+	s.clicksM.Lock()
+	for ck, n := range s.clicks {
+		if ck.id == id {
+			add(ck.hour, n)
+		}
+	}
+	s.clicksM.Unlock()
+
+	cs = &ClickSeries{
+		Granularity: granularity,
+		From:        bucket(from),
+		To:          to.UTC(),
+		Buckets:     []ClickBucket{},
+	}
+	for ts, n := range counts {
+		cs.Buckets = append(cs.Buckets, ClickBucket{Time: time.Unix(ts, 0).UTC(), Clicks: n})
+		cs.Total += n
+	}
+	sort.Slice(cs.Buckets, func(i, j int) bool { return cs.Buckets[i].Time.Before(cs.Buckets[j].Time) })
+	return
+}
+
+// clickMove is a bucket to roll up (to != nil) or to delete
+type clickMove struct {
+	from, to []byte
+	clicks   uint64
+}
+
+// CompactClicks rolls up the hourly clicks older than stats.hourly_retention
+// days into daily clicks and deletes the daily clicks older than
+// stats.daily_retention days (if set). Only whole days are rolled up
+func (s *Store) CompactClicks(now time.Time) (err error) {
+	hourlyLimit := startOfDay(now.AddDate(0, 0, -s.Config.Stats.HourlyRetention)).Unix()
+	dailyLimit := int64(0)
+	if s.Config.Stats.DailyRetention > 0 {
+		dailyLimit = startOfDay(now.AddDate(0, 0, -s.Config.Stats.DailyRetention)).Unix()
+	}
+	moves := []clickMove{}
+	err = s.db.View(func(txn Txn) (err error) {
+		scan := func(gran byte, limit int64) error {
+			it := txn.Iterate([]byte{keyClicksPrefix, gran})
+			defer it.Close()
+			for it.Next() {
+				k := it.Key()
+				id, ts, err := parseKeyClick(k)
+				if err != nil || ts >= limit {
+					continue
+				}
+				m := clickMove{from: k}
+				// hours of days past the daily retention are just deleted
+				if dayTs := startOfDay(time.Unix(ts, 0)).Unix(); gran == clicksHour && dayTs >= dailyLimit {
+					v, err := it.Value()
+					if err != nil {
+						return err
+					}
+					m.to = keyClick(clicksDay, id, dayTs)
+					m.clicks = atoi(v)
+				}
+				moves = append(moves, m)
+			}
+			return nil
+		}
+		if err = scan(clicksHour, hourlyLimit); err != nil {
+			return
+		}
+		if dailyLimit > 0 {
+			err = scan(clicksDay, dailyLimit)
+		}
+		return
+	})
+	if err != nil {
+		return
+	}
+	for start := 0; start < len(moves); start += clicksCompactBatch {
+		end := start + clicksCompactBatch
+		if end > len(moves) {
+			end = len(moves)
+		}
+		err = s.db.Update(func(txn Txn) (err error) {
+			for _, m := range moves[start:end] {
+				if m.to != nil {
+					dbSetUint64(txn, m.to, dbGetUint64(txn, m.to)+m.clicks)
+				}
+				if err = txn.Delete(m.from); err != nil {
+					return
+				}
+			}
+			return
+		})
+		if err != nil {
+			return
+		}
+	}
+	mlog.Trace("CompactClicks() %d buckets", len(moves))
+	return
+}
+
+// runCompactClicks compacts the clicks every interval until the store is closed
+func (s *Store) runCompactClicks(interval time.Duration) {
+	defer s.wg.Done()
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-t.C:
+			if err := s.CompactClicks(time.Now()); err != nil {
+				mlog.Warning("Error compacting the url clicks: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package urlstore
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClickSeries(t *testing.T) {
+	t.Parallel()
+	s := openBackendStore(t, BackendMemory, buildConifgTest())
+	defer s.Close()
+	id, err := s.UpsertURLSimple(&URLReq{URL: "https://ilij.li"})
+	require.NoError(t, err)
+	day := time.Date(2019, 12, 20, 0, 0, 0, 0, time.UTC)
+	for _, h := range []int{1, 1, 2, 23, 25, 26} {
+		s.recordClick(id, day.Add(time.Duration(h)*time.Hour+time.Minute))
+	}
+	// half of the clicks are flushed
+	require.NoError(t, s.Flush())
+	s.recordClick(id, day.Add(time.Hour))
+	s.recordClick(id, day.Add(48*time.Hour))
+
+	tests := []struct {
+		name        string
+		granularity string
+		from, to    time.Time
+		want        []ClickBucket
+		wantErr     error
+	}{
+		{"hours", GranularityHour, day, day.Add(24 * time.Hour), []ClickBucket{
+			{day.Add(time.Hour), 3},
+			{day.Add(2 * time.Hour), 1},
+			{day.Add(23 * time.Hour), 1},
+		}, nil},
+		{"hours unaligned", GranularityHour, day.Add(90 * time.Minute), day.Add(24 * time.Hour), []ClickBucket{
+			{day.Add(time.Hour), 3},
+			{day.Add(2 * time.Hour), 1},
+			{day.Add(23 * time.Hour), 1},
+		}, nil},
+		{"days", GranularityDay, day, day.AddDate(0, 0, 7), []ClickBucket{
+			{day, 5},
+			{day.AddDate(0, 0, 1), 2},
+			{day.AddDate(0, 0, 2), 1},
+		}, nil},
+		{"empty", GranularityDay, day.AddDate(0, 0, 7), day.AddDate(0, 0, 14), []ClickBucket{}, nil},
+		{"invalid", "week", day, day.AddDate(0, 0, 7), nil, ErrInvalidGranularity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs, err := s.ClickSeries(id, tt.granularity, tt.from, tt.to)
+			require.Equal(t, tt.wantErr, err)
+			if err != nil {
+				return
+			}
+			require.Equal(t, tt.want, cs.Buckets)
+		})
+	}
+	_, err = s.ClickSeries("notfound", GranularityDay, day, day.AddDate(0, 0, 7))
+	require.Equal(t, ErrKeyNotFound, err)
+}
+
+// failingBackend is a backend whose updates can be made to fail,
+// or to fail when they write more than maxWrites keys
+type failingBackend struct {
+	Backend
+	fail      bool
+	maxWrites int
+}
+
+func (b *failingBackend) Update(fn func(txn Txn) error) error {
+	if b.fail {
+		return fmt.Errorf("update failed")
+	}
+	return b.Backend.Update(func(txn Txn) error {
+		return fn(&limitedTxn{Txn: txn, max: b.maxWrites})
+	})
+}
+
+// limitedTxn fails the writes past max, like a transaction too big
+type limitedTxn struct {
+	Txn
+	max, writes int
+}
+
+func (t *limitedTxn) Set(k, v []byte) error {
+	if t.writes++; t.max > 0 && t.writes > t.max {
+		return fmt.Errorf("transaction too big")
+	}
+	return t.Txn.Set(k, v)
+}
+
+func TestFlushClicks(t *testing.T) {
+	t.Parallel()
+	db := &failingBackend{Backend: NewMemoryBackend()}
+	s, err := NewStoreWithBackend(buildConifgTest(), db)
+	require.NoError(t, err)
+	defer s.Close()
+	id, err := s.UpsertURLSimple(&URLReq{URL: "https://ilij.li"})
+	require.NoError(t, err)
+	_, err = s.Redirect(id, &Visit{IP: "1.1.1.1", UserAgent: "Mozilla/5.0 (iPhone)"})
+	require.NoError(t, err)
+
+	// the pending clicks are kept when the commit fails
+	db.fail = true
+	require.Error(t, s.Flush())
+	db.fail = false
+	require.NoError(t, s.Flush())
+	cs, err := s.ClickSeries(id, GranularityHour, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), cs.Total)
+	n, err := s.Uniques(id)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), n)
+	require.Empty(t, s.clicks)
+	require.Empty(t, s.breakdown)
+	require.Empty(t, s.uniques)
+
+	// the clicks of an url removed in the meantime are not written
+	other, err := s.UpsertURLSimple(&URLReq{URL: "https://ilij.li/other"})
+	require.NoError(t, err)
+	_, err = s.Redirect(other, &Visit{IP: "1.1.1.1", UserAgent: "Mozilla/5.0 (iPhone)"})
+	require.NoError(t, err)
+	err = s.db.Update(func(txn Txn) error {
+		k, _ := keyURL(other)
+		return txn.Delete(k)
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.Flush())
+	s.db.View(func(txn Txn) error {
+		for _, kind := range []byte{clicksHour, clicksBreakdown, clicksUniques} {
+			it := txn.Iterate(keyClicks(kind, other))
+			require.False(t, it.Next())
+			it.Close()
+		}
+		return nil
+	})
+}
+
+func TestFlushClicksBatches(t *testing.T) {
+	t.Parallel()
+	db := &failingBackend{Backend: NewMemoryBackend(), maxWrites: clicksFlushBatch}
+	s, err := NewStoreWithBackend(buildConifgTest(), db)
+	require.NoError(t, err)
+	defer s.Close()
+	// more pending counters than a transaction can write
+	n, now := 2*clicksFlushBatch+1, time.Now()
+	for i := 0; i < n; i++ {
+		id := fmt.Sprint("id", i)
+		require.NoError(t, s.Upsert(&URLInfo{ID: id, URL: "https://ilij.li"}))
+		s.recordClick(id, now)
+		s.recordVariant(id, fmt.Sprint("https://ilij.li/", i))
+		s.recordUnique(id, &Visit{IP: fmt.Sprint(i)}, now)
+	}
+	require.NoError(t, s.Flush())
+	require.Empty(t, s.clicks)
+	require.Empty(t, s.breakdown)
+	require.Empty(t, s.uniques)
+	count := func(kind byte) (c int) {
+		s.db.View(func(txn Txn) error {
+			it := txn.Iterate([]byte{keyClicksPrefix, kind})
+			defer it.Close()
+			for it.Next() {
+				c++
+			}
+			return nil
+		})
+		return
+	}
+	require.Equal(t, n, count(clicksHour))
+	require.Equal(t, n, count(clicksBreakdown))
+	require.Equal(t, n, count(clicksUniques))
+}
+
+func TestCompactClicks(t *testing.T) {
+	t.Parallel()
+	cfg := buildConifgTest()
+	cfg.Stats.HourlyRetention = 2
+	cfg.Stats.DailyRetention = 10
+	s := openBackendStore(t, BackendMemory, cfg)
+	defer s.Close()
+	id, err := s.UpsertURLSimple(&URLReq{URL: "https://ilij.li"})
+	require.NoError(t, err)
+	now := time.Date(2019, 12, 20, 12, 0, 0, 0, time.UTC)
+	for _, d := range []int{-20, -5, -5, -3, -2, -1, 0} {
+		s.recordClick(id, now.AddDate(0, 0, d))
+	}
+	require.NoError(t, s.Flush())
+	require.NoError(t, s.CompactClicks(now))
+
+	// only the last 2 days are hourly
+	cs, err := s.ClickSeries(id, GranularityHour, now.AddDate(0, 0, -30), now.Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, []ClickBucket{
+		{now.AddDate(0, 0, -2), 1},
+		{now.AddDate(0, 0, -1), 1},
+		{now, 1},
+	}, cs.Buckets)
+	// the clicks older than 10 days are gone
+	cs, err = s.ClickSeries(id, GranularityDay, now.AddDate(0, 0, -30), now.Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, uint64(6), cs.Total)
+	require.Equal(t, ClickBucket{startOfDay(now.AddDate(0, 0, -5)), 2}, cs.Buckets[0])
+	// compaction is idempotent
+	require.NoError(t, s.CompactClicks(now))
+	cs, err = s.ClickSeries(id, GranularityDay, now.AddDate(0, 0, -30), now.Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, uint64(6), cs.Total)
+	// the clicks are deleted with the url
+	require.NoError(t, s.DeleteURL(id))
+	err = s.db.View(func(txn Txn) error {
+		it := txn.Iterate([]byte{keyClicksPrefix})
+		defer it.Close()
+		require.False(t, it.Next())
+		return nil
+	})
+	require.NoError(t, err)
+}
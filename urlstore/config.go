@@ -3,6 +3,7 @@ package urlstore
 import (
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"strings"
 	"time"
 
@@ -13,22 +14,35 @@ import (
 
 // ServerConfig configuration for the server
 type ServerConfig struct {
-	APIKey string `yaml:"api_key" mapstructure:"api_key"`
-	Host   string `yaml:"host" mapstructure:"host"`
-	Port   int    `yaml:"port" mapstructure:"port"`
-	DbPath string `yaml:"db_path" mapstructure:"db_path"`
+	APIKey          string `yaml:"api_key" mapstructure:"api_key"`
+	Host            string `yaml:"host" mapstructure:"host"`
+	Port            int    `yaml:"port" mapstructure:"port"`
+	DbPath          string `yaml:"db_path" mapstructure:"db_path"`
+	DbBackend       string `yaml:"db_backend" mapstructure:"db_backend"`
+	ShutdownTimeout int    `yaml:"shutdown_timeout" mapstructure:"shutdown_timeout"`
+	MetricsKey      string `yaml:"metrics_key" mapstructure:"metrics_key"`
 }
 
 //ShortIDConfig configuration for the short id
 type ShortIDConfig struct {
-	Alphabet             string    `yaml:"alphabet" mapstructure:"alphabet"`
-	Length               int       `yaml:"length" mapstructure:"length"`
-	MaxRequests          uint64    `yaml:"max_requests" mapstructure:"max_requests"`
-	TTL                  uint64    `yaml:"ttl" mapstructure:"ttl"`
-	ExpireOn             time.Time `yaml:"expire_on" mapstructure:"expire_on"`
-	RootRedirectURL      string    `yaml:"root_redirect_url" mapstructure:"root_redirect_url"`
-	ExpiredRedirectURL   string    `yaml:"expired_redirect_url" mapstructure:"expired_redirect_url"`
-	ExhaustedRedirectURL string    `yaml:"exhausted_redirect_url" mapstructure:"exhausted_redirect_url"`
+	Alphabet               string    `yaml:"alphabet" mapstructure:"alphabet"`
+	Length                 int       `yaml:"length" mapstructure:"length"`
+	MaxRequests            uint64    `yaml:"max_requests" mapstructure:"max_requests"`
+	TTL                    uint64    `yaml:"ttl" mapstructure:"ttl"`
+	ExpireOn               time.Time `yaml:"expire_on" mapstructure:"expire_on"`
+	IdleTTL                uint64    `yaml:"idle_ttl" mapstructure:"idle_ttl"`
+	WindowMaxRequests      uint64    `yaml:"window_max_requests" mapstructure:"window_max_requests"`
+	WindowSize             uint64    `yaml:"window_size" mapstructure:"window_size"`
+	RootRedirectURL        string    `yaml:"root_redirect_url" mapstructure:"root_redirect_url"`
+	ExpiredRedirectURL     string    `yaml:"expired_redirect_url" mapstructure:"expired_redirect_url"`
+	ExhaustedRedirectURL   string    `yaml:"exhausted_redirect_url" mapstructure:"exhausted_redirect_url"`
+	InactiveRedirectURL    string    `yaml:"inactive_redirect_url" mapstructure:"inactive_redirect_url"`
+	RedirectStatus         int       `yaml:"redirect_status" mapstructure:"redirect_status"`
+	ForwardQuery           bool      `yaml:"forward_query" mapstructure:"forward_query"`
+	ForwardPath            bool      `yaml:"forward_path" mapstructure:"forward_path"`
+	PasswordMaxAttempts    int       `yaml:"password_max_attempts" mapstructure:"password_max_attempts"`
+	PasswordMaxURLAttempts int       `yaml:"password_max_url_attempts" mapstructure:"password_max_url_attempts"`
+	PasswordLockout        int       `yaml:"password_lockout" mapstructure:"password_lockout"`
 }
 
 // TuningConfig fine tuning configuration
@@ -41,13 +55,47 @@ type TuningConfig struct {
 	URLCacheSize           int     `yaml:"url_cache_size" mapstructure:"url_cache_size"`
 	BckCSVIterPrefetchSize int     `yaml:"export_iterator_prefetch_size" mapstructure:"export_iterator_prefetch_size"`
 	APIKeyHeaderName       string  `yaml:"api_key_header_name" mapstructure:"api_key_header_name"`
+	FlushInterval          int     `yaml:"flush_interval" mapstructure:"flush_interval"`
+}
+
+// StatsConfig configuration for the urls statistics
+type StatsConfig struct {
+	// HourlyRetention days to keep the hourly clicks before rolling them up to daily
+	HourlyRetention int `yaml:"hourly_retention" mapstructure:"hourly_retention"`
+	// DailyRetention days to keep the daily clicks, 0 keeps them forever
+	DailyRetention int `yaml:"daily_retention" mapstructure:"daily_retention"`
+	// GeoDBPath is the path of a MaxMind (.mmdb) database, empty disables geo
+	GeoDBPath string `yaml:"geo_db_path" mapstructure:"geo_db_path"`
+	// GeoCity enables the per city clicks, it requires a city database
+	GeoCity bool `yaml:"geo_city" mapstructure:"geo_city"`
+}
+
+// SweepConfig configuration of the expired urls sweeper
+type SweepConfig struct {
+	// Interval seconds between the sweeps, the sweeper is opt-in,
+	// 0 (the default) disables it
+	Interval int `yaml:"interval" mapstructure:"interval"`
+	// GracePeriod seconds the urls are kept after they expired or were exhausted
+	GracePeriod int `yaml:"grace_period" mapstructure:"grace_period"`
+	// Mode is archive to move the urls to the archive or delete to remove them
+	Mode string `yaml:"mode" mapstructure:"mode"`
+}
+
+// TrashConfig configuration of the deleted urls
+type TrashConfig struct {
+	// Retention days to keep the deleted urls before purging them, at least 1
+	Retention int `yaml:"retention" mapstructure:"retention"`
 }
 
 // ConfigSchema define the configuration object
 type ConfigSchema struct {
-	Server  ServerConfig  `yaml:"server" mapstructure:"server"`
-	ShortID ShortIDConfig `yaml:"short_id" mapstructure:"short_id"`
-	Tuning  TuningConfig  `yaml:"tuning" mapstructure:"tuning"`
+	Server    ServerConfig         `yaml:"server" mapstructure:"server"`
+	ShortID   ShortIDConfig        `yaml:"short_id" mapstructure:"short_id"`
+	Stats     StatsConfig          `yaml:"stats" mapstructure:"stats"`
+	Sweep     SweepConfig          `yaml:"sweep" mapstructure:"sweep"`
+	Trash     TrashConfig          `yaml:"trash" mapstructure:"trash"`
+	Tuning    TuningConfig         `yaml:"tuning" mapstructure:"tuning"`
+	Campaigns map[string]UTMParams `yaml:"campaigns,omitempty" mapstructure:"campaigns"`
 }
 
 func empty(s string) bool {
@@ -60,11 +108,27 @@ func Defaults() {
 	viper.SetDefault("server.host", "0.0.0.0")
 	viper.SetDefault("server.port", 1804)
 	viper.SetDefault("server.db_path", "distill.db")
+	viper.SetDefault("server.db_backend", BackendBadger)
+	viper.SetDefault("server.shutdown_timeout", 15)
 	// for short id
 	viper.SetDefault("short_id.root_redirect_url", "https://github.com/noandrea/distill/wikis/welcome")
 	viper.SetDefault("short_id.expired_redirect_url", "https://github.com/noandrea/distill/wikis/Expired-URL")
+	viper.SetDefault("short_id.inactive_redirect_url", "https://github.com/noandrea/distill/wikis/Inactive-URL")
 	viper.SetDefault("short_id.alphabet", "abcdefghkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789")
 	viper.SetDefault("short_id.length", 6)
+	viper.SetDefault("short_id.redirect_status", http.StatusFound)
+	viper.SetDefault("short_id.password_max_attempts", 5)
+	viper.SetDefault("short_id.password_max_url_attempts", 100)
+	viper.SetDefault("short_id.password_lockout", 900)
+	// for stats
+	viper.SetDefault("stats.hourly_retention", 7)
+	viper.SetDefault("stats.daily_retention", 0)
+	// for the sweeper
+	viper.SetDefault("sweep.interval", 0)
+	viper.SetDefault("sweep.grace_period", 0)
+	viper.SetDefault("sweep.mode", SweepArchive)
+	// for the trash
+	viper.SetDefault("trash.retention", 30)
 	// for tuning
 	viper.SetDefault("tuning.stats_events_worker_num", 1)
 	viper.SetDefault("tuning.stats_cache_size", 1024)
@@ -75,6 +139,7 @@ func Defaults() {
 	viper.SetDefault("tuning.url_cache_size", 2048)
 	viper.SetDefault("tuning.bck_csv_iter_prefetch_size", 2048)
 	viper.SetDefault("tuning.api_key_header_name", "X-API-KEY")
+	viper.SetDefault("tuning.flush_interval", 10)
 }
 
 //Defaults generate configuration defaults
@@ -83,12 +148,28 @@ func (c *ConfigSchema) Defaults() {
 	common.DefaultIfEmptyStr(&c.Server.Host, "0.0.0.0")
 	common.DefaultIfEmptyInt(&c.Server.Port, 1804)
 	common.DefaultIfEmptyStr(&c.Server.DbPath, "distill.db")
+	common.DefaultIfEmptyStr(&c.Server.DbBackend, BackendBadger)
+	common.DefaultIfEmptyInt(&c.Server.ShutdownTimeout, 15)
 
 	// for short id
 	common.DefaultIfEmptyStr(&c.ShortID.RootRedirectURL, "https://discover.distill.plus")
 	common.DefaultIfEmptyStr(&c.ShortID.ExpiredRedirectURL, "https://discover.distill.plus")
+	common.DefaultIfEmptyStr(&c.ShortID.InactiveRedirectURL, "https://discover.distill.plus")
 	common.DefaultIfEmptyStr(&c.ShortID.Alphabet, "abcdefghkmnpqrstuvwxyzACDEFGHJKLMNPQRSTUVWXYZ2345679")
 	common.DefaultIfEmptyInt(&c.ShortID.Length, 6)
+	common.DefaultIfEmptyInt(&c.ShortID.RedirectStatus, http.StatusFound)
+	common.DefaultIfEmptyInt(&c.ShortID.PasswordMaxAttempts, 5)
+	common.DefaultIfEmptyInt(&c.ShortID.PasswordMaxURLAttempts, 100)
+	common.DefaultIfEmptyInt(&c.ShortID.PasswordLockout, 900)
+
+	// for stats
+	common.DefaultIfEmptyInt(&c.Stats.HourlyRetention, 7)
+
+	// for the sweeper
+	common.DefaultIfEmptyStr(&c.Sweep.Mode, SweepArchive)
+
+	// for the trash
+	common.DefaultIfEmptyInt(&c.Trash.Retention, 30)
 
 	// For tuning
 	common.DefaultIfEmptyInt(&c.Tuning.StatsEventsWorkerNum, 1)
@@ -101,6 +182,7 @@ func (c *ConfigSchema) Defaults() {
 	common.DefaultIfEmptyInt(&c.Tuning.URLCacheSize, 2048)
 	common.DefaultIfEmptyInt(&c.Tuning.BckCSVIterPrefetchSize, 2048)
 	common.DefaultIfEmptyStr(&c.Tuning.APIKeyHeaderName, "X-API-KEY")
+	common.DefaultIfEmptyInt(&c.Tuning.FlushInterval, 10)
 }
 
 //Validate configuration
@@ -110,6 +192,10 @@ func (c *ConfigSchema) Validate() {
 		panic("server.api_key cannot be empty")
 	}
 
+	if b := strings.ToLower(c.Server.DbBackend); b != BackendBadger && b != BackendMemory {
+		panic(fmt.Sprint("server.db_backend must be ", BackendBadger, " or ", BackendMemory))
+	}
+
 	if c.ShortID.Length < 3 {
 		panic("short_id.length must be at least 3")
 	}
@@ -118,19 +204,45 @@ func (c *ConfigSchema) Validate() {
 		panic(fmt.Sprint("short_id.alphabet must be at least ", c.ShortID.Length, " characters long"))
 	}
 
+	if !ValidRedirectStatus(c.ShortID.RedirectStatus) {
+		panic(fmt.Sprint("short_id.redirect_status must be one of ", RedirectStatuses))
+	}
+
+	if c.ShortID.PasswordMaxAttempts <= 0 || c.ShortID.PasswordMaxURLAttempts <= 0 || c.ShortID.PasswordLockout <= 0 {
+		panic("short_id.password_max_attempts, short_id.password_max_url_attempts and short_id.password_lockout must be > 0")
+	}
+
+	if c.Stats.DailyRetention < 0 {
+		panic("stats.daily_retention cannot be negative")
+	}
+
+	if m := strings.ToLower(c.Sweep.Mode); m != SweepArchive && m != SweepDelete {
+		panic(fmt.Sprint("sweep.mode must be ", SweepArchive, " or ", SweepDelete))
+	}
+
+	if c.Tuning.FlushInterval < 0 {
+		panic("tuning.flush_interval cannot be negative")
+	}
+
+	if c.Sweep.Interval < 0 || c.Sweep.GracePeriod < 0 {
+		panic("sweep.interval and sweep.grace_period cannot be negative")
+	}
+
+	if c.Trash.Retention <= 0 {
+		panic("trash.retention must be at least 1 day")
+	}
+
 	if c.Tuning.DbGCDiscardRation <= 0 || c.Tuning.DbGCDiscardRation > 1 {
 		panic(fmt.Sprint("tuning.db_gc_discard_ration must be > 0 and < 1"))
 	}
 }
 
-// Config system configuration
-var Config ConfigSchema
-
 // GenerateDefaultConfig generate a default configuration file an writes it in the outFile
 func GenerateDefaultConfig(outFile, version string) {
-	Config.Defaults()
-	Config.Server.APIKey = common.GenerateSecret()
-	b, _ := yaml.Marshal(Config)
+	cfg := ConfigSchema{}
+	cfg.Defaults()
+	cfg.Server.APIKey = common.GenerateSecret()
+	b, _ := yaml.Marshal(cfg)
 	data := strings.Join([]string{
 		"#",
 		fmt.Sprintf("# Default configuration for Distill v%s", version),
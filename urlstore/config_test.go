@@ -0,0 +1,38 @@
+package urlstore
+
+import (
+	"testing"
+)
+
+func TestConfigSchema_Validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		change    func(c *ConfigSchema)
+		wantPanic bool
+	}{
+		{"defaults", func(c *ConfigSchema) {}, false},
+		{"password max attempts 0", func(c *ConfigSchema) { c.ShortID.PasswordMaxAttempts = 0 }, true},
+		{"password max attempts negative", func(c *ConfigSchema) { c.ShortID.PasswordMaxAttempts = -1 }, true},
+		{"password max url attempts 0", func(c *ConfigSchema) { c.ShortID.PasswordMaxURLAttempts = 0 }, true},
+		{"password lockout 0", func(c *ConfigSchema) { c.ShortID.PasswordLockout = 0 }, true},
+		{"trash retention 0", func(c *ConfigSchema) { c.Trash.Retention = 0 }, true},
+		{"trash retention negative", func(c *ConfigSchema) { c.Trash.Retention = -1 }, true},
+		{"flush interval 0", func(c *ConfigSchema) { c.Tuning.FlushInterval = 0 }, false},
+		{"flush interval negative", func(c *ConfigSchema) { c.Tuning.FlushInterval = -1 }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := ConfigSchema{}
+			c.Server.APIKey = "test"
+			c.Server.DbBackend = BackendMemory
+			c.Defaults()
+			tt.change(&c)
+			defer func() {
+				if r := recover(); (r != nil) != tt.wantPanic {
+					t.Errorf("Validate() panic = %v, wantPanic %v", r, tt.wantPanic)
+				}
+			}()
+			c.Validate()
+		})
+	}
+}
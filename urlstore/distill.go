@@ -4,6 +4,8 @@ import (
 	"encoding/csv"
 	"fmt"
 	"io"
+	"math/rand"
+	"net/http"
 	net "net/url"
 	"os"
 	"regexp"
@@ -16,11 +18,11 @@ import (
 
 // generateID generates a new id
 // it is guaranteed that returns an id of at least 1 character
-func generateID() (shortID string) {
-	a := Config.ShortID.Alphabet
+func (s *Store) generateID() (shortID string) {
+	a := s.Config.ShortID.Alphabet
 	l := 1
-	if Config.ShortID.Length > 1 {
-		l = Config.ShortID.Length
+	if s.Config.ShortID.Length > 1 {
+		l = s.Config.ShortID.Length
 	}
 	// a and l are validated before
 	shortID, _ = common.RandomString(a, l)
@@ -29,13 +31,86 @@ func generateID() (shortID string) {
 
 // UpsertURLSimple insert or updae an url
 // shortcut for UpsertURL(true, true, time.Now())
-func UpsertURLSimple(url *URLReq) (id string, err error) {
-	return UpsertURL(url, true, true, time.Now())
+func (s *Store) UpsertURLSimple(url *URLReq) (id string, err error) {
+	return s.UpsertURL(url, true, true, time.Now())
 }
 
 // UpsertURL insert or udpdate a url mapping
-func UpsertURL(url *URLReq, forceAlphabet, forceLength bool, boundAt time.Time) (id string, err error) {
+func (s *Store) UpsertURL(url *URLReq, forceAlphabet, forceLength bool, boundAt time.Time) (id string, err error) {
 	// preprocess the url and generates the id if necessary
+	u, err := s.newURLInfo(url, boundAt)
+	if err != nil {
+		return
+	}
+	// cleanup the string id
+	u.ID = strings.TrimSpace(url.ID)
+	// process url id
+	if len(u.ID) == 0 {
+		err = s.Insert(u)
+	} else {
+		// TODO: check longest allowed key in badger
+		p := fmt.Sprintf("[^%s]", regexp.QuoteMeta(s.Config.ShortID.Alphabet))
+		m, _ := regexp.MatchString(p, url.ID)
+		if forceAlphabet && m {
+			err = fmt.Errorf("ID %v doesn't match alphabet and forceAlphabet is active", url.ID)
+			return "", err
+		}
+		if forceLength && len(url.ID) != s.Config.ShortID.Length {
+			err = fmt.Errorf("ID %v doesn't match length and forceLength len %v, required %v", url.ID, len(url.ID), s.Config.ShortID.Length)
+			return "", err
+		}
+		if s.InTrash(u.ID) {
+			return "", ErrURLTrashed
+		}
+		if _, aerr := s.PeekArchived(u.ID); aerr == nil {
+			return "", ErrURLArchived
+		}
+		err = s.replace(u)
+	}
+
+	if err == nil {
+		// collect statistics
+		s.pushEvent(&URLOp{
+			opcode: opcodeInsert,
+			ID:     u.ID,
+			err:    err,
+		})
+	}
+	return u.ID, err
+}
+
+// UpsertURLs inserts count urls with generated ids for the same
+// request, for example to send a one time url to each invitee
+func (s *Store) UpsertURLs(url *URLReq, count int, boundAt time.Time) (ids []string, err error) {
+	if len(strings.TrimSpace(url.ID)) > 0 {
+		err = fmt.Errorf("the id cannot be set for a batch of urls")
+		return
+	}
+	if count <= 0 || count > BatchMaxCount {
+		err = ErrInvalidBatchCount
+		return
+	}
+	// the request is validated (and the password hashed) only once
+	u, err := s.newURLInfo(url, boundAt)
+	if err != nil {
+		return
+	}
+	for i := 0; i < count; i++ {
+		nu := *u
+		if err = s.Insert(&nu); err != nil {
+			return
+		}
+		s.pushEvent(&URLOp{
+			opcode: opcodeInsert,
+			ID:     nu.ID,
+		})
+		ids = append(ids, nu.ID)
+	}
+	return
+}
+
+// newURLInfo validates an url request and returns the url for it, without the id
+func (s *Store) newURLInfo(url *URLReq, boundAt time.Time) (u *URLInfo, err error) {
 	// chech that the target url is a valid url
 	if _, err = net.Parse(url.URL); err != nil {
 		mlog.Info("%s", url.URL)
@@ -56,55 +131,308 @@ func UpsertURL(url *URLReq, forceAlphabet, forceLength bool, boundAt time.Time)
 			return
 		}
 	}
+	if len(url.InactiveURL) > 0 {
+		if _, err = net.Parse(url.InactiveURL); err != nil {
+			return
+		}
+	}
+	schedule, err := validateSchedule(url.Schedule)
+	if err != nil {
+		return
+	}
+	if err = validateVariants(url.Variants); err != nil {
+		return
+	}
+	if err = validateRules(url.Rules); err != nil {
+		return
+	}
+	languages, err := languageList(url.Languages)
+	if err != nil {
+		return
+	}
+	countries, err := countryList(url.Countries)
+	if err != nil {
+		return
+	}
+	if url.RedirectStatus != 0 && !ValidRedirectStatus(url.RedirectStatus) {
+		err = ErrInvalidRedirectStatus
+		return
+	}
+	if err = s.validateCampaign(url.Campaign); err != nil {
+		return
+	}
+	passwordHash, err := hashPassword(url.Password)
+	if err != nil {
+		return
+	}
 
 	// set the binding date
-	u := &URLInfo{
+	u = &URLInfo{
 		BountAt:      boundAt,
 		URL:          url.URL,
 		ExhaustedURL: url.ExhaustedURL,
 		TTL:          url.TTL,
 		ExpiredURL:   url.ExpiredURL,
+		Variants:     url.Variants,
+		Rules:        url.Rules,
+		Languages:    languages,
+		Countries:    countries,
+		// the global status and passthrough are applied on redirect
+		RedirectStatus: uint32(url.RedirectStatus),
+		ForwardQuery:   passthroughOption(url.ForwardQuery),
+		ForwardPath:    passthroughOption(url.ForwardPath),
+		Campaign:       url.Campaign,
+		PasswordHash:   passwordHash,
+		OneTime:        url.OneTime,
+		ActiveFrom:     url.ActiveFrom,
+		InactiveURL:    url.InactiveURL,
+		Schedule:       schedule,
 	}
+	u.SetUTM(url.UTMParams)
 	// the local expiration always take priority
 	u.ExpireOn = calculateExpiration(u, url.TTL, url.ExpireOn)
 	if u.ExpireOn.IsZero() {
 		// global expiration
-		u.ExpireOn = calculateExpiration(u, Config.ShortID.TTL, Config.ShortID.ExpireOn)
+		u.ExpireOn = calculateExpiration(u, s.Config.ShortID.TTL, s.Config.ShortID.ExpireOn)
+	}
+	// the local idle ttl always take priority
+	u.IdleTTL = url.IdleTTL
+	if u.IdleTTL == 0 {
+		u.IdleTTL = s.Config.ShortID.IdleTTL
+	}
+	// the local window always take priority
+	u.WindowMaxRequests, u.WindowSize = url.WindowMaxRequests, url.WindowSize
+	if u.WindowMaxRequests == 0 {
+		u.WindowMaxRequests = s.Config.ShortID.WindowMaxRequests
+	}
+	if u.WindowSize == 0 {
+		u.WindowSize = s.Config.ShortID.WindowSize
+	}
+	if u.WindowMaxRequests > 0 && u.WindowSize == 0 {
+		err = ErrInvalidWindow
+		return
 	}
 	// set max requests, the local version always has priority
 	u.MaxRequests = url.MaxRequests
 	if u.MaxRequests == 0 {
-		u.MaxRequests = Config.ShortID.MaxRequests
+		u.MaxRequests = s.Config.ShortID.MaxRequests
 	}
-	// cleanup the string id
-	u.ID = strings.TrimSpace(url.ID)
-	// process url id
-	if len(u.ID) == 0 {
-		err = Insert(u)
-	} else {
-		// TODO: check longest allowed key in badger
-		p := fmt.Sprintf("[^%s]", regexp.QuoteMeta(Config.ShortID.Alphabet))
-		m, _ := regexp.MatchString(p, url.ID)
-		if forceAlphabet && m {
-			err = fmt.Errorf("ID %v doesn't match alphabet and forceAlphabet is active", url.ID)
-			return "", err
+	return
+}
+
+// PatchURL updates only the fields set in the patch, the counter and
+// the creation date are preserved. If ifMatch, the value of an If-Match header,
+// is not empty the update is applied only if it matches the current ETag of the url
+func (s *Store) PatchURL(id string, p *URLPatch, ifMatch string) (u *URLInfo, err error) {
+	// validate the urls
+	if p.URL != nil && len(strings.TrimSpace(*p.URL)) == 0 {
+		err = fmt.Errorf("url cannot be empty")
+		return
+	}
+	for _, v := range []*string{p.URL, p.ExhaustedURL, p.ExpiredURL, p.InactiveURL} {
+		if v == nil || len(*v) == 0 {
+			continue
 		}
-		if forceLength && len(url.ID) != Config.ShortID.Length {
-			err = fmt.Errorf("ID %v doesn't match length and forceLength len %v, required %v", url.ID, len(url.ID), Config.ShortID.Length)
-			return "", err
+		if _, err = net.Parse(*v); err != nil {
+			return
+		}
+	}
+	var schedule []*Change
+	if p.Schedule != nil {
+		if schedule, err = validateSchedule(*p.Schedule); err != nil {
+			return
+		}
+	}
+	if p.Variants != nil {
+		if err = validateVariants(*p.Variants); err != nil {
+			return
+		}
+	}
+	if p.Rules != nil {
+		if err = validateRules(*p.Rules); err != nil {
+			return
+		}
+	}
+	languages, err := languageList(p.Languages)
+	if err != nil {
+		return
+	}
+	countries, err := countryList(p.Countries)
+	if err != nil {
+		return
+	}
+	if p.RedirectStatus != nil && *p.RedirectStatus != 0 && !ValidRedirectStatus(*p.RedirectStatus) {
+		err = ErrInvalidRedirectStatus
+		return
+	}
+	if p.Campaign != nil {
+		if err = s.validateCampaign(*p.Campaign); err != nil {
+			return
 		}
-		err = Upsert(u)
 	}
+	var passwordHash string
+	if p.Password != nil {
+		if passwordHash, err = hashPassword(*p.Password); err != nil {
+			return
+		}
+	}
+	s.upM.Lock()
+	defer s.upM.Unlock()
+	current, err := s.Peek(id)
+	if err != nil {
+		return
+	}
+	if len(ifMatch) > 0 && !matchETag(ifMatch, current.ETag()) {
+		err = ErrETagMismatch
+		return
+	}
+	// work on a copy, the current one might be in the cache
+	nu := *current
+	u = &nu
+	if p.URL != nil {
+		u.URL = *p.URL
+	}
+	if p.ExhaustedURL != nil {
+		u.ExhaustedURL = *p.ExhaustedURL
+	}
+	if p.ExpiredURL != nil {
+		u.ExpiredURL = *p.ExpiredURL
+	}
+	if p.MaxRequests != nil {
+		u.MaxRequests = *p.MaxRequests
+	}
+	if p.Variants != nil {
+		u.Variants = *p.Variants
+	}
+	if p.Rules != nil {
+		u.Rules = *p.Rules
+	}
+	if p.Languages != nil {
+		u.Languages = languages
+	}
+	if p.Countries != nil {
+		u.Countries = countries
+	}
+	if p.RedirectStatus != nil {
+		u.RedirectStatus = uint32(*p.RedirectStatus)
+	}
+	if p.ForwardQuery != nil {
+		u.ForwardQuery = passthroughOption(p.ForwardQuery)
+	}
+	if p.ForwardPath != nil {
+		u.ForwardPath = passthroughOption(p.ForwardPath)
+	}
+	if p.UTMParams != nil {
+		u.SetUTM(*p.UTMParams)
+	}
+	if p.Campaign != nil {
+		u.Campaign = *p.Campaign
+	}
+	if p.Password != nil {
+		u.PasswordHash = passwordHash
+	}
+	if p.OneTime != nil {
+		u.OneTime = *p.OneTime
+	}
+	if p.ActiveFrom != nil {
+		u.ActiveFrom = *p.ActiveFrom
+	}
+	if p.InactiveURL != nil {
+		u.InactiveURL = *p.InactiveURL
+	}
+	if p.Schedule != nil {
+		u.Schedule = schedule
+	}
+	if p.IdleTTL != nil {
+		u.IdleTTL = *p.IdleTTL
+	}
+	if p.WindowMaxRequests != nil {
+		u.WindowMaxRequests = *p.WindowMaxRequests
+	}
+	if p.WindowSize != nil {
+		u.WindowSize = *p.WindowSize
+	}
+	if u.WindowMaxRequests > 0 && u.WindowSize == 0 {
+		err = ErrInvalidWindow
+		return
+	}
+	if p.TTL != nil || p.ExpireOn != nil {
+		// keep the expiration date set explicitly when only the ttl
+		// changes, the one derived from the previous ttl is recalculated
+		expireOn := u.ExpireOn
+		if p.ExpireOn != nil {
+			expireOn = *p.ExpireOn
+		} else if u.TTL > 0 && u.ExpireOn.Equal(u.ExpirationDate()) {
+			expireOn = time.Time{}
+		}
+		if p.TTL != nil {
+			u.TTL = *p.TTL
+		}
+		u.ExpireOn = calculateExpiration(u, u.TTL, expireOn)
+	}
+	// keep the clicks received in the meantime,
+	// unless the url has been deleted in the meantime
+	um := s.urlM.of(id)
+	um.Lock()
+	latest, _, err := s.peek(id)
+	if err != nil {
+		um.Unlock()
+		return nil, err
+	}
+	u.Counter, u.LastVisit = latest.Counter, latest.LastVisit
+	u.WindowStart, u.WindowCounter = latest.WindowStart, latest.WindowCounter
+	if err = s.Upsert(u); err != nil {
+		um.Unlock()
+		return
+	}
+	s.uc.Set(id, u)
+	um.Unlock()
+	s.pushEvent(&URLOp{
+		opcode: opcodeUpdate,
+		ID:     id,
+	})
+	return
+}
 
-	if err == nil {
-		// collect statistics
-		pushEvent(&URLOp{
-			opcode: opcodeInsert,
-			ID:     u.ID,
-			err:    err,
-		})
+// validateVariants checks that the variants have a valid url and a weight
+func validateVariants(variants []*Variant) (err error) {
+	for _, v := range variants {
+		if v == nil || len(strings.TrimSpace(v.URL)) == 0 || v.Weight == 0 {
+			return ErrInvalidVariant
+		}
+		if _, err = net.Parse(v.URL); err != nil {
+			return
+		}
 	}
-	return u.ID, err
+	return
+}
+
+// pickVariant returns the index of the variant for a visit, a returning
+// client keeps its variant while its url is still a variant, the others
+// get one at random by weight
+func pickVariant(variants []*Variant, sticky string) int {
+	if len(sticky) > 0 {
+		for i, v := range variants {
+			if v.Key() == sticky {
+				return i
+			}
+		}
+	}
+	var total int64
+	for _, v := range variants {
+		total += int64(v.Weight)
+	}
+	if total == 0 {
+		return rand.Intn(len(variants))
+	}
+	n := rand.Int63n(total)
+	for i, v := range variants {
+		if n < int64(v.Weight) {
+			return i
+		}
+		n -= int64(v.Weight)
+	}
+	return len(variants) - 1
 }
 
 // calculateExpiration calculate the expiration of a url
@@ -120,13 +448,13 @@ func calculateExpiration(u *URLInfo, ttl uint64, expireDate time.Time) (expire t
 }
 
 // DeleteURL delete a url mapping
-func DeleteURL(id string) (err error) {
-	err = Delete(id)
+func (s *Store) DeleteURL(id string) (err error) {
+	err = s.Delete(id)
 	if err != nil {
 		return
 	}
 	// collect statistics
-	pushEvent(&URLOp{
+	s.pushEvent(&URLOp{
 		opcode: opcodeDelete,
 		ID:     id,
 	})
@@ -135,54 +463,192 @@ func DeleteURL(id string) (err error) {
 
 // GetURLRedirect retrieve the redicrect url associated to an id
 // it also fire an event of tipe opcodeGet
-func GetURLRedirect(id string) (redirectURL string, err error) {
-	urlInfo, err := Get(id)
+func (s *Store) GetURLRedirect(id string) (redirectURL string, err error) {
+	return s.GetURLRedirectVisit(id, nil)
+}
+
+// GetURLRedirectVisit retrieve the redirect url associated to an id
+// for a visit, the visit is counted in the url breakdown (if not nil)
+func (s *Store) GetURLRedirectVisit(id string, v *Visit) (redirectURL string, err error) {
+	r, err := s.Redirect(id, v)
+	return r.URL, err
+}
+
+// Redirect retrieve the redirect target associated to an id for a visit,
+// the target is the one of the first rule matching the user agent of the
+// visit, then the one of the country of the visit, then the one of the
+// language preferred by the visit, then for urls with variants the target
+// is chosen by weight unless the visit already has a variant, otherwise
+// it is the url as changed by its schedule. The protected urls require
+// the password in the visit, the inactive ones go to the inactive url and
+// the rate limited ones to the exhausted url while their window is full
+func (s *Store) Redirect(id string, v *Visit) (r Redirect, err error) {
+	// the inactive urls are not counted and the
+	// protected ones only with the right password
+	if u, perr := s.Peek(id); perr == nil {
+		if !u.Active(time.Now()) {
+			err = ErrURLInactive
+			r.URL, r.Status = u.InactiveURL, http.StatusFound
+			common.DefaultIfEmptyStr(&r.URL, s.Config.ShortID.InactiveRedirectURL)
+			s.metrics.redirects.WithLabelValues(RedirectInactive).Inc()
+			return
+		}
+		if u.Protected() {
+			if err = s.checkPassword(u, v); err != nil {
+				return
+			}
+		}
+	}
+	urlInfo, err := s.Get(id)
 	if err != nil {
+		s.metrics.redirects.WithLabelValues(RedirectNotFound).Inc()
 		return
 	}
+	if v != nil {
+		s.recordVisit(id, v)
+		s.recordUnique(id, v, time.Now())
+	}
 
 	urlop := &URLOp{ID: urlInfo.ID}
 
-	if !urlInfo.ExpireOn.IsZero() && time.Now().After(urlInfo.ExpireOn) {
+	if urlInfo.Expired(time.Now()) {
 		mlog.Trace("Expire date for %v, limit %v, requests %v", urlInfo.ID, urlInfo.Counter, urlInfo.MaxRequests)
 		err = ErrURLExpired
-		common.DefaultIfEmptyStr(&urlInfo.ExpiredURL, Config.ShortID.ExpiredRedirectURL)
-		redirectURL = urlInfo.ExpiredURL
+		// the expired and exhausted redirects are temporary
+		r.URL, r.Status = urlInfo.ExpiredURL, http.StatusFound
+		common.DefaultIfEmptyStr(&r.URL, s.Config.ShortID.ExpiredRedirectURL)
 
 		urlop.err = err
 		urlop.opcode = opcodeExpired
-		pushEvent(urlop)
+		s.pushEvent(urlop)
+		s.metrics.redirects.WithLabelValues(RedirectExpired).Inc()
 		return
 	}
-	if urlInfo.MaxRequests > 0 && urlInfo.Counter > urlInfo.MaxRequests {
-		mlog.Trace("Expire max request for %v, limit %v, requests %v", urlInfo.ID, urlInfo.Counter, urlInfo.MaxRequests)
+	if l := urlInfo.RequestsLimit(); l > 0 && urlInfo.Counter > l {
+		mlog.Trace("Expire max request for %v, limit %v, requests %v", urlInfo.ID, urlInfo.Counter, l)
 		err = ErrURLExhausted
-		common.DefaultIfEmptyStr(&urlInfo.ExhaustedURL, Config.ShortID.ExhaustedRedirectURL)
-		redirectURL = urlInfo.ExhaustedURL
+		r.URL, r.Status = urlInfo.ExhaustedURL, http.StatusFound
+		common.DefaultIfEmptyStr(&r.URL, s.Config.ShortID.ExhaustedRedirectURL)
 
 		urlop.err = err
 		urlop.opcode = opcodeExpired
-		pushEvent(urlop)
+		s.pushEvent(urlop)
+		s.metrics.redirects.WithLabelValues(RedirectExhausted).Inc()
+		return
+	}
+	if urlInfo.RateLimited() && urlInfo.WindowCounter > urlInfo.WindowMaxRequests {
+		mlog.Trace("Rate limit for %v, limit %v, requests %v", urlInfo.ID, urlInfo.WindowMaxRequests, urlInfo.WindowCounter)
+		err = ErrURLRateLimited
+		r.URL, r.Status = urlInfo.ExhaustedURL, http.StatusFound
+		common.DefaultIfEmptyStr(&r.URL, s.Config.ShortID.ExhaustedRedirectURL)
+
+		urlop.err = err
+		urlop.opcode = opcodeExpired
+		s.pushEvent(urlop)
+		s.metrics.redirects.WithLabelValues(RedirectLimited).Inc()
 		return
 	}
 
 	// collect statistics
 	urlop.err = err
 	urlop.opcode = opcodeGet
-	pushEvent(urlop)
+	s.pushEvent(urlop)
+	s.metrics.redirects.WithLabelValues(RedirectOK).Inc()
 	// return the redirectUrl
-	redirectURL = urlInfo.URL
+	r.URL, r.Variant = s.target(urlInfo, v)
+	r.Status = s.redirectStatus(urlInfo)
+	r.URL = s.tag(urlInfo, r.URL)
+	if v != nil {
+		r.URL = s.passthrough(urlInfo, r.URL, v)
+	}
 	return
 }
 
+// target returns the target of an url for a visit, the first matching
+// rule, country or language or else one of the variants or the url
+func (s *Store) target(u *URLInfo, v *Visit) (target, variant string) {
+	var ok bool
+	if v != nil && len(u.Rules) > 0 {
+		if target, ok = matchRules(u.Rules, v.UserAgent); ok {
+			return
+		}
+	}
+	if v != nil && len(u.Countries) > 0 {
+		if target, ok = s.matchCountry(u.Countries, v); ok {
+			return
+		}
+	}
+	if v != nil && len(u.Languages) > 0 {
+		if target, ok = matchLanguage(u.Languages, v.AcceptLanguage); ok {
+			return
+		}
+	}
+	if len(u.Variants) > 0 {
+		var sticky string
+		if v != nil {
+			sticky = v.Variant
+		}
+		i := pickVariant(u.Variants, sticky)
+		target, variant = u.Variants[i].URL, u.Variants[i].Key()
+		s.recordVariant(u.ID, target)
+		return
+	}
+	return u.URLAt(time.Now()), ""
+}
+
+// redirectStatus returns the http status of the redirects of an url,
+// the one of the url or the global one, 302 if none is set
+func (s *Store) redirectStatus(u *URLInfo) int {
+	if u.RedirectStatus != 0 {
+		return int(u.RedirectStatus)
+	}
+	if s.Config.ShortID.RedirectStatus != 0 {
+		return s.Config.ShortID.RedirectStatus
+	}
+	return http.StatusFound
+}
+
 // GetURLInfo retrieve the url info associated to an id
-func GetURLInfo(id string) (urlInfo *URLInfo, err error) {
-	urlInfo, err = Peek(id)
+func (s *Store) GetURLInfo(id string) (urlInfo *URLInfo, err error) {
+	urlInfo, err = s.Peek(id)
+	return
+}
+
+// ListURLs returns up to limit urls matching the filter,
+// starting from the cursor returned by a previous call (empty for the first page)
+func (s *Store) ListURLs(f *URLFilter, cursor string, limit int) (l *URLList, err error) {
+	l = &URLList{URLs: []*URLInfo{}}
+	i := s.NewURLIterator()
+	defer i.Close()
+	if len(cursor) > 0 {
+		if err = i.Seek(cursor); err != nil {
+			return nil, err
+		}
+	}
+	now := time.Now()
+	for i.HasNext() {
+		u, err := i.NextURL()
+		if err != nil {
+			return nil, err
+		}
+		// the cache has the most recent counters
+		if uic, err := s.uc.GetIFPresent(u.ID); err == nil {
+			u = uic.(*URLInfo)
+		}
+		if !f.Match(u, now) {
+			continue
+		}
+		if len(l.URLs) >= limit {
+			l.Next = u.ID
+			break
+		}
+		l.URLs = append(l.URLs, u)
+	}
 	return
 }
 
 // ImportCSV import urls from a csv file
-func ImportCSV(inFile string) (rows int, err error) {
+func (s *Store) ImportCSV(inFile string) (rows int, err error) {
 	fp, err := os.Open(inFile)
 	if err != nil {
 		return
@@ -210,7 +676,7 @@ func ImportCSV(inFile string) (rows int, err error) {
 			mlog.Error(err)
 			break
 		}
-		_, err = UpsertURL(u, false, false, time.Now())
+		_, err = s.UpsertURL(u, false, false, time.Now())
 		if err != nil {
 			mlog.Error(err)
 			break
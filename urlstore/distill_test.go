@@ -5,8 +5,10 @@ import (
 	"io/ioutil"
 	"log"
 	"math/rand"
+	"net/http"
 	"path/filepath"
 	"regexp"
+	"sync"
 	"testing"
 	"time"
 
@@ -16,18 +18,31 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+var logOnce sync.Once
+
 func setupLog() {
-	mlog.DefaultFlags = log.Ltime | log.Lmicroseconds | log.Lshortfile
-	//mlog.Start(mlog.LevelTrace, "")
-	mlog.Start(mlog.LevelInfo, "")
+	logOnce.Do(func() {
+		mlog.DefaultFlags = log.Ltime | log.Lmicroseconds | log.Lshortfile
+		//mlog.Start(mlog.LevelTrace, "")
+		mlog.Start(mlog.LevelInfo, "")
+	})
 }
 
-func buildConifgTest() {
+// openStore opens a store for a test configuration
+func openStore(t testing.TB, cfg ConfigSchema) *Store {
+	s, err := NewStore(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func buildConifgTest() (cfg ConfigSchema) {
 	setupLog()
 	// path
 	path, _ := ioutil.TempDir("/tmp/", "distill")
 	fmt.Println("test db folder is ", path)
-	Config = ConfigSchema{
+	cfg = ConfigSchema{
 		Server: ServerConfig{
 			DbPath: path,
 			APIKey: common.GenerateSecret(),
@@ -37,15 +52,16 @@ func buildConifgTest() {
 			Length:   6,
 		},
 	}
-	Config.Defaults()
-	Config.Validate()
+	cfg.Defaults()
+	cfg.Validate()
+	return
 }
 
-func buildConifgPanicTest() {
+func buildConifgPanicTest() (cfg ConfigSchema) {
 	setupLog()
 	path := " cann not exists / ssa "
 	fmt.Println("test db folder is ", path)
-	Config = ConfigSchema{
+	cfg = ConfigSchema{
 		Server: ServerConfig{
 			DbPath: path,
 			APIKey: common.GenerateSecret(),
@@ -55,15 +71,16 @@ func buildConifgPanicTest() {
 			Length:   6,
 		},
 	}
-	Config.Defaults()
-	Config.Validate()
+	cfg.Defaults()
+	cfg.Validate()
+	return
 }
 
-func buildConifgTestShortIDParams(alphabet string, length int) {
+func buildConifgTestShortIDParams(alphabet string, length int) (cfg ConfigSchema) {
 	setupLog()
 	path, _ := ioutil.TempDir("/tmp/", "distill")
 	fmt.Println("test db folder is ", path)
-	Config = ConfigSchema{
+	cfg = ConfigSchema{
 		Server: ServerConfig{
 			DbPath: path,
 			APIKey: common.GenerateSecret(),
@@ -78,15 +95,16 @@ func buildConifgTestShortIDParams(alphabet string, length int) {
 			StatsEventsWorkerNum: 2,
 		},
 	}
-	Config.Defaults()
-	Config.Validate()
+	cfg.Defaults()
+	cfg.Validate()
+	return
 }
 
-func buildConifgTestExpireParams(ttl, maxr uint64, expire time.Time) {
+func buildConifgTestExpireParams(ttl, maxr uint64, expire time.Time) (cfg ConfigSchema) {
 	setupLog()
 	path, _ := ioutil.TempDir("/tmp/", "distill")
 	fmt.Println("test db folder is ", path)
-	Config = ConfigSchema{
+	cfg = ConfigSchema{
 		Server: ServerConfig{
 			DbPath: path,
 			APIKey: common.GenerateSecret(),
@@ -102,8 +120,9 @@ func buildConifgTestExpireParams(ttl, maxr uint64, expire time.Time) {
 			StatsEventsWorkerNum: 20,
 		},
 	}
-	Config.Defaults()
-	Config.Validate()
+	cfg.Defaults()
+	cfg.Validate()
+	return
 }
 
 func TestGenerateID(t *testing.T) {
@@ -121,14 +140,16 @@ func TestGenerateID(t *testing.T) {
 		{"abcdefghkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789", 30, "[iIl1o0O]"},
 	}
 	for _, tt := range tests {
-		Config = ConfigSchema{
-			ShortID: ShortIDConfig{
-				Alphabet: tt.Alphabet,
-				Length:   tt.Length,
+		s := &Store{
+			Config: ConfigSchema{
+				ShortID: ShortIDConfig{
+					Alphabet: tt.Alphabet,
+					Length:   tt.Length,
+				},
 			},
 		}
 		t.Run(tt.Alphabet, func(t *testing.T) {
-			gotShortID := generateID()
+			gotShortID := s.generateID()
 			if len(gotShortID) != tt.Length {
 				t.Errorf("GenerateID() = %v, len = %v, want %v", gotShortID, len(gotShortID), tt.Length)
 			}
@@ -141,9 +162,9 @@ func TestGenerateID(t *testing.T) {
 }
 
 func TestUpsertURLQuick(t *testing.T) {
-	buildConifgTestShortIDParams("abcdefghkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789", 6)
-	NewSession()
-	defer CloseSession()
+	t.Parallel()
+	s := openStore(t, buildConifgTestShortIDParams("abcdefghkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789", 6))
+	defer s.Close()
 	// test urls
 	tests := []string{
 		"https://battle.example.com/approval/arm.aspx",
@@ -250,12 +271,13 @@ func TestUpsertURLQuick(t *testing.T) {
 	// test random urls
 	for _, u := range tests {
 		urlrq := &URLReq{URL: u}
-		_, err := UpsertURL(urlrq, false, false, time.Now())
+		_, err := s.UpsertURL(urlrq, false, false, time.Now())
 		require.NoError(t, err)
 	}
 }
 
 func TestUpsertURL(t *testing.T) {
+	t.Parallel()
 	type args struct {
 		forceAlphabet bool
 		forceLength   bool
@@ -376,13 +398,12 @@ func TestUpsertURL(t *testing.T) {
 			},
 		},
 	}
-	buildConifgTestShortIDParams("abcdefghkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789", 6)
-	NewSession()
-	defer CloseSession()
+	s := openStore(t, buildConifgTestShortIDParams("abcdefghkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789", 6))
+	defer s.Close()
 	ids := make(map[string]bool)
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			id, err := UpsertURL(tt.url, tt.args.forceAlphabet, tt.args.forceLength, time.Now())
+			id, err := s.UpsertURL(tt.url, tt.args.forceAlphabet, tt.args.forceLength, time.Now())
 			mlog.Info("upsert url %v, %v", id, err)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("UpsertURL() error = %v, wantErr %v", err, tt.wantErr)
@@ -403,13 +424,14 @@ func TestUpsertURL(t *testing.T) {
 	id := "samesame"
 	ur := "https://wikipedia.li"
 
-	ui, _ := GetURLInfo(id)
+	ui, _ := s.GetURLInfo(id)
 	if ui.URL != ur {
 		t.Errorf("UpsertURL()  %v, want %v", ui.URL, ur)
 	}
 }
 
 func TestDeleteURL(t *testing.T) {
+	t.Parallel()
 	type args struct {
 		url URLReq
 	}
@@ -431,24 +453,22 @@ func TestDeleteURL(t *testing.T) {
 			url:     nil,
 		},
 	}
-	buildConifgTestShortIDParams("abcdefghkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789", 6)
-
-	NewSession()
-	defer CloseSession()
+	s := openStore(t, buildConifgTestShortIDParams("abcdefghkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789", 6))
+	defer s.Close()
 	for _, tt := range tests {
 		var id string
 		if tt.url != nil {
-			id, _ = UpsertURL(tt.url, false, false, time.Now())
+			id, _ = s.UpsertURL(tt.url, false, false, time.Now())
 		}
 
 		t.Run(tt.name, func(t *testing.T) {
-			if err := DeleteURL(id); (err != nil) != tt.wantErr {
+			if err := s.DeleteURL(id); (err != nil) != tt.wantErr {
 				t.Errorf("DeleteURL() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 
 		if tt.url != nil {
-			if _, err := GetURLInfo(id); err == nil {
+			if _, err := s.GetURLInfo(id); err == nil {
 				t.Errorf("DeleteURL() not deleted")
 			}
 		}
@@ -457,6 +477,7 @@ func TestDeleteURL(t *testing.T) {
 }
 
 func TestGetURL(t *testing.T) {
+	t.Parallel()
 	type args struct {
 		id string
 	}
@@ -492,19 +513,18 @@ func TestGetURL(t *testing.T) {
 			wantURL: "https://ilij.li/?param=4",
 		},
 	}
-	buildConifgTestShortIDParams("abcdefghkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789", 6)
-	NewSession()
-	defer CloseSession()
+	s := openStore(t, buildConifgTestShortIDParams("abcdefghkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789", 6))
+	defer s.Close()
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			id := "notfound"
 			if !tt.wantErr {
-				id, _ = UpsertURL(&URLReq{URL: tt.wantURL}, true, true, time.Now())
+				id, _ = s.UpsertURL(&URLReq{URL: tt.wantURL}, true, true, time.Now())
 			}
 			t.Log("id:", id)
 			// a short pause to make sure the data is written
 			time.Sleep(time.Duration(10) * time.Millisecond)
-			gotURL, err := GetURLRedirect(id)
+			gotURL, err := s.GetURLRedirect(id)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetURL() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -520,6 +540,7 @@ func TestGetURL(t *testing.T) {
 }
 
 func TestExpireRequestsUrl(t *testing.T) {
+	t.Parallel()
 	tests := []struct {
 		name    string
 		numrq   int
@@ -581,15 +602,14 @@ func TestExpireRequestsUrl(t *testing.T) {
 		},
 	}
 	var zeroTime time.Time
-	buildConifgTestExpireParams(0, 20, zeroTime)
-	NewSession()
-	defer CloseSession()
+	s := openStore(t, buildConifgTestExpireParams(0, 20, zeroTime))
+	defer s.Close()
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			id, err := UpsertURL(&tt.param, true, true, time.Now())
+			id, err := s.UpsertURL(&tt.param, true, true, time.Now())
 			// consume all the requests
 			for i := 0; i < tt.numrq; i++ {
-				_, err = GetURLRedirect(id)
+				_, err = s.GetURLRedirect(id)
 			}
 			// this should be a not found now for the expired
 			hasErr := (err != nil)
@@ -601,15 +621,16 @@ func TestExpireRequestsUrl(t *testing.T) {
 	}
 	// get the stats
 	//TODO: time.Sleep(time.Duration(10) * time.Millisecond)
-	s := GetStats()
-	t.Log(s)
+	st := s.GetStats()
+	t.Log(st)
 	var expected uint64 = 6
-	if s.Urls != expected {
-		t.Errorf("ExpireUrl() count = %v, want %v", s.Urls, expected)
+	if st.Urls != expected {
+		t.Errorf("ExpireUrl() count = %v, want %v", st.Urls, expected)
 	}
 }
 
 func TestExpireTTLUrl(t *testing.T) {
+	t.Parallel()
 	tests := []struct {
 		name    string
 		wait    int
@@ -656,18 +677,17 @@ func TestExpireTTLUrl(t *testing.T) {
 		},
 	}
 	var zeroTime time.Time
-	buildConifgTestExpireParams(0, 0, zeroTime)
-	NewSession()
-	defer CloseSession()
+	s := openStore(t, buildConifgTestExpireParams(0, 0, zeroTime))
+	defer s.Close()
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			id, _ := UpsertURL(&tt.param, true, true, time.Now())
+			id, _ := s.UpsertURL(&tt.param, true, true, time.Now())
 			//mlog.Info("-- upsert %s --", id)
 			// consume all the requests
 			time.Sleep(time.Duration(tt.wait) * time.Second)
 			now := time.Now()
-			_, err := GetURLRedirect(id)
-			u, _ := GetURLInfo(id)
+			_, err := s.GetURLRedirect(id)
+			u, _ := s.GetURLInfo(id)
 			fmt.Println(tt.name, tt.wantErr, "\nbat", u.BountAt, "\nexp", u.ExpireOn, "\nnow", now.UTC(), "\ndif", now.Sub(u.BountAt))
 			//mlog.Info("-- << end  %s --", id)
 			// this should be a not found now for the expired
@@ -679,18 +699,16 @@ func TestExpireTTLUrl(t *testing.T) {
 		})
 	}
 	// get the stats
-	s := GetStats()
-	t.Log(s)
-	if s.Urls != 4 {
-		t.Errorf("ExpireUrl() count = %v, want %v", s.Urls, 4)
+	st := s.GetStats()
+	t.Log(st)
+	if st.Urls != 4 {
+		t.Errorf("ExpireUrl() count = %v, want %v", st.Urls, 4)
 	}
 }
 
 func BenchmarkSession(b *testing.B) {
-	buildConifgTestShortIDParams("abcdefghkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789", 6)
-
-	NewSession()
-	defer CloseSession()
+	s := openStore(b, buildConifgTestShortIDParams("abcdefghkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789", 6))
+	defer s.Close()
 
 	numIds := 10000 // means 10000
 	var ids []string
@@ -700,7 +718,7 @@ func BenchmarkSession(b *testing.B) {
 		ur := &URLReq{
 			URL: fmt.Sprintf("http://ilij.li/long=%d", i),
 		}
-		id, err := UpsertURL(ur, true, true, time.Now())
+		id, err := s.UpsertURL(ur, true, true, time.Now())
 		if err != nil {
 			b.Error("eror inserting url", err)
 		}
@@ -712,25 +730,26 @@ func BenchmarkSession(b *testing.B) {
 	b.Run("thet test", func(b *testing.B) {
 		for i := 0; i < b.N; i++ {
 			idx := rand.Intn(numIds)
-			GetURLRedirect(ids[idx])
+			s.GetURLRedirect(ids[idx])
 		}
-		b.Log(GetStats())
+		b.Log(s.GetStats())
 	})
 
 	b.Run("thet test", func(b *testing.B) {
 		for i := 0; i < b.N; i++ {
 			if 1%10 == 0 {
 				idx := rand.Intn(numIds)
-				DeleteURL(ids[idx])
+				s.DeleteURL(ids[idx])
 			}
 
 		}
-		b.Log(GetStats())
+		b.Log(s.GetStats())
 	})
 
 }
 
 func TestImportCSV(t *testing.T) {
+	t.Parallel()
 	type args struct {
 		inFile string
 	}
@@ -754,10 +773,9 @@ func TestImportCSV(t *testing.T) {
 		},
 	}
 	for _, tt := range tests {
-		buildConifgTest()
-		NewSession()
+		s := openStore(t, buildConifgTest())
 		t.Run(tt.name, func(t *testing.T) {
-			gotRows, err := ImportCSV(tt.args.inFile)
+			gotRows, err := s.ImportCSV(tt.args.inFile)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ImportCSV() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -766,11 +784,12 @@ func TestImportCSV(t *testing.T) {
 				t.Errorf("ImportCSV() = %v, want %v", gotRows, tt.wantRows)
 			}
 		})
-		CloseSession()
+		s.Close()
 	}
 }
 
 func TestBackupRestore(t *testing.T) {
+	t.Parallel()
 
 	tmpdir, _ := ioutil.TempDir("/tmp/", "distill-bckrestore")
 
@@ -804,24 +823,281 @@ func TestBackupRestore(t *testing.T) {
 		},
 	}
 	for _, tt := range tests {
-		buildConifgTest()
-		NewSession()
+		s := openStore(t, buildConifgTest())
 		t.Run(tt.name, func(t *testing.T) {
 			for i := 0; i < tt.wantRows; i++ {
-				UpsertURLSimple(&URLReq{URL: fmt.Sprintf("http://ex.com/v=%d", i)})
+				s.UpsertURLSimple(&URLReq{URL: fmt.Sprintf("http://ex.com/v=%d", i)})
 			}
-			err := Backup(tt.args.bckFile)
+			err := s.Backup(tt.args.bckFile)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Backup() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			_, err = Restore(tt.args.bckFile)
+			_, err = s.Restore(tt.args.bckFile)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Restore() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 
 		})
-		CloseSession()
+		s.Close()
+	}
+}
+
+func TestListURLs(t *testing.T) {
+	t.Parallel()
+	s := openBackendStore(t, BackendMemory, buildConifgTest())
+	defer s.Close()
+	start := time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 25; i++ {
+		u := &URLReq{URL: fmt.Sprintf("https://ex.com/even/%d", i)}
+		if i%2 == 1 {
+			u.URL = fmt.Sprintf("https://ex.com/odd/%d", i)
+		}
+		if i%5 == 0 {
+			u.MaxRequests = 1
+		}
+		id, err := s.UpsertURL(u, false, false, start.Add(time.Duration(i)*24*time.Hour))
+		require.NoError(t, err)
+		if i%5 == 0 {
+			s.GetURLRedirect(id)
+		}
+	}
+	tests := []struct {
+		name   string
+		filter URLFilter
+		limit  int
+		want   int
+		pages  int
+	}{
+		{"all", URLFilter{}, 10, 25, 3},
+		{"all one page", URLFilter{}, 100, 25, 1},
+		{"odd", URLFilter{URL: "/odd/"}, 5, 12, 3},
+		{"exhausted", URLFilter{State: URLStateExhausted}, 10, 5, 1},
+		{"active", URLFilter{State: URLStateActive}, 10, 20, 2},
+		{"expired", URLFilter{State: URLStateExpired}, 10, 0, 1},
+		{"created range", URLFilter{CreatedFrom: start.Add(24 * time.Hour), CreatedTo: start.Add(72 * time.Hour)}, 2, 3, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids := map[string]bool{}
+			cursor, pages := "", 0
+			for {
+				l, err := s.ListURLs(&tt.filter, cursor, tt.limit)
+				require.NoError(t, err)
+				require.True(t, len(l.URLs) <= tt.limit)
+				for _, u := range l.URLs {
+					require.False(t, ids[u.ID], "duplicated id %v", u.ID)
+					ids[u.ID] = true
+				}
+				pages++
+				if len(l.Next) == 0 {
+					break
+				}
+				cursor = l.Next
+			}
+			require.Equal(t, tt.want, len(ids))
+			require.Equal(t, tt.pages, pages)
+		})
 	}
 }
+
+func TestPatchURL(t *testing.T) {
+	t.Parallel()
+	s := openBackendStore(t, BackendMemory, buildConifgTest())
+	defer s.Close()
+	boundAt := time.Now().Add(-time.Minute)
+	id, err := s.UpsertURL(&URLReq{URL: "https://ilij.li/a", MaxRequests: 10, ExpiredURL: "https://ilij.li/expired"}, false, false, boundAt)
+	require.NoError(t, err)
+	s.GetURLRedirect(id)
+	s.GetURLRedirect(id)
+	u, _ := s.GetURLInfo(id)
+	etag := u.ETag()
+
+	str := func(v string) *string { return &v }
+	num := func(v uint64) *uint64 { return &v }
+	// redirects do not change the etag
+	s.GetURLRedirect(id)
+	u, _ = s.GetURLInfo(id)
+	require.Equal(t, etag, u.ETag())
+	// patch the target only
+	u, err = s.PatchURL(id, &URLPatch{URL: str("https://ilij.li/b")}, etag)
+	require.NoError(t, err)
+	require.Equal(t, "https://ilij.li/b", u.URL)
+	require.Equal(t, "https://ilij.li/expired", u.ExpiredURL)
+	require.Equal(t, uint64(10), u.MaxRequests)
+	require.Equal(t, uint64(3), u.Counter)
+	require.True(t, boundAt.Equal(u.BountAt))
+	require.NotEqual(t, etag, u.ETag())
+	// a stale etag is rejected
+	_, err = s.PatchURL(id, &URLPatch{MaxRequests: num(1)}, etag)
+	require.Equal(t, ErrETagMismatch, err)
+	// the wildcard always match
+	u, err = s.PatchURL(id, &URLPatch{MaxRequests: num(1), TTL: num(3600)}, "*")
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), u.MaxRequests)
+	require.True(t, boundAt.Add(time.Hour).Equal(u.ExpireOn))
+	_, err = s.GetURLRedirect(id)
+	require.Equal(t, ErrURLExhausted, err)
+	// the stored version is updated
+	u, _ = s.GetURLInfo(id)
+	require.Equal(t, "https://ilij.li/b", u.URL)
+	// a new ttl recalculates the expiration derived from the previous ttl
+	u, err = s.PatchURL(id, &URLPatch{TTL: num(60)}, "")
+	require.NoError(t, err)
+	require.True(t, boundAt.Add(time.Minute).Equal(u.ExpireOn))
+	// but keeps the expiration date set explicitly
+	expireOn := boundAt.Add(48 * time.Hour)
+	u, err = s.PatchURL(id, &URLPatch{ExpireOn: &expireOn}, "")
+	require.NoError(t, err)
+	require.True(t, expireOn.Equal(u.ExpireOn))
+	u, err = s.PatchURL(id, &URLPatch{TTL: num(7200)}, "")
+	require.NoError(t, err)
+	require.Equal(t, uint64(7200), u.TTL)
+	require.True(t, expireOn.Equal(u.ExpireOn))
+	// invalid patches
+	_, err = s.PatchURL(id, &URLPatch{URL: str(" ")}, "")
+	require.Error(t, err)
+	_, err = s.PatchURL("notfound", &URLPatch{URL: str("https://ilij.li/c")}, "")
+	require.Equal(t, ErrKeyNotFound, err)
+}
+
+func TestVariants(t *testing.T) {
+	t.Parallel()
+	s := openBackendStore(t, BackendMemory, buildConifgTest())
+	defer s.Close()
+	a, b := "https://ilij.li/a", "https://ilij.li/b"
+	ka, kb := (&Variant{URL: a}).Key(), (&Variant{URL: b}).Key()
+	require.NotEqual(t, ka, kb)
+	id, err := s.UpsertURLSimple(&URLReq{URL: "https://ilij.li", Variants: []*Variant{{URL: a, Weight: 70}, {URL: b, Weight: 30}}})
+	require.NoError(t, err)
+	// invalid variants
+	_, err = s.UpsertURLSimple(&URLReq{URL: "https://ilij.li", Variants: []*Variant{{URL: a}}})
+	require.Equal(t, ErrInvalidVariant, err)
+	_, err = s.UpsertURLSimple(&URLReq{URL: "https://ilij.li", Variants: []*Variant{{Weight: 1}}})
+	require.Equal(t, ErrInvalidVariant, err)
+
+	// the variants are chosen by weight
+	clicks := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		r, err := s.Redirect(id, nil)
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{a: ka, b: kb}[r.URL], r.Variant)
+		clicks[r.URL]++
+	}
+	require.InDelta(t, 700, clicks[a], 70)
+	require.InDelta(t, 300, clicks[b], 70)
+	// a returning client keeps its variant
+	for i := 0; i < 10; i++ {
+		r, err := s.Redirect(id, &Visit{Variant: kb})
+		require.NoError(t, err)
+		require.Equal(t, Redirect{URL: b, Status: 302, Variant: kb}, r)
+	}
+	clicks[b] += 10
+	// unless the variant does not exist anymore
+	r, err := s.Redirect(id, &Visit{Variant: "1"})
+	require.NoError(t, err)
+	clicks[r.URL]++
+
+	// the clicks are counted per variant
+	require.NoError(t, s.Flush())
+	vs, err := s.VariantClicks(id)
+	require.NoError(t, err)
+	require.Equal(t, []VariantStats{
+		{URL: a, Weight: 70, Clicks: uint64(clicks[a])},
+		{URL: b, Weight: 30, Clicks: uint64(clicks[b])},
+	}, vs)
+	bd, err := s.Breakdown(id, 1)
+	require.NoError(t, err)
+	require.Len(t, bd[BreakdownVariant], 1)
+
+	// the variants can be replaced, a returning client
+	// keeps its variant even if it changed position
+	c := "https://ilij.li/c"
+	u, err := s.PatchURL(id, &URLPatch{Variants: &[]*Variant{{URL: b, Weight: 1}, {URL: c, Weight: 1000}}}, "")
+	require.NoError(t, err)
+	require.Len(t, u.Variants, 2)
+	r, err = s.Redirect(id, &Visit{Variant: kb})
+	require.NoError(t, err)
+	require.Equal(t, Redirect{URL: b, Status: 302, Variant: kb}, r)
+	u, err = s.PatchURL(id, &URLPatch{Variants: &[]*Variant{{URL: b, Weight: 1}}}, "")
+	require.NoError(t, err)
+	require.Len(t, u.Variants, 1)
+	r, err = s.Redirect(id, &Visit{Variant: ka})
+	require.NoError(t, err)
+	require.Equal(t, Redirect{URL: b, Status: 302, Variant: kb}, r)
+	// or removed
+	_, err = s.PatchURL(id, &URLPatch{Variants: &[]*Variant{}}, "")
+	require.NoError(t, err)
+	r, err = s.Redirect(id, nil)
+	require.NoError(t, err)
+	require.Equal(t, Redirect{URL: "https://ilij.li", Status: 302}, r)
+	vs, err = s.VariantClicks(id)
+	require.NoError(t, err)
+	require.Nil(t, vs)
+
+	// urls without variants have no variant breakdown
+	id, err = s.UpsertURLSimple(&URLReq{URL: "https://ilij.li"})
+	require.NoError(t, err)
+	s.Redirect(id, nil)
+	bd, err = s.Breakdown(id, 1)
+	require.NoError(t, err)
+	require.NotContains(t, bd, BreakdownVariant)
+
+	// csv import
+	req := &URLReq{}
+	require.NoError(t, req.UnmarshalRecord([]string{"https://ilij.li", "", "", "", "", "1:" + a + " 2:" + b}))
+	require.Equal(t, []*Variant{{URL: a, Weight: 1}, {URL: b, Weight: 2}}, req.Variants)
+}
+
+func TestRedirectStatus(t *testing.T) {
+	t.Parallel()
+	cfg := buildConifgTest()
+	cfg.ShortID.RedirectStatus = http.StatusTemporaryRedirect
+	s := openBackendStore(t, BackendMemory, cfg)
+	defer s.Close()
+
+	tests := []struct {
+		name    string
+		status  int
+		want    int
+		wantErr error
+	}{
+		{"global", 0, http.StatusTemporaryRedirect, nil},
+		{"moved permanently", http.StatusMovedPermanently, http.StatusMovedPermanently, nil},
+		{"permanent redirect", http.StatusPermanentRedirect, http.StatusPermanentRedirect, nil},
+		{"see other", http.StatusSeeOther, http.StatusSeeOther, nil},
+		{"not a redirect", http.StatusOK, 0, ErrInvalidRedirectStatus},
+		{"not modified", http.StatusNotModified, 0, ErrInvalidRedirectStatus},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := s.UpsertURLSimple(&URLReq{URL: "https://ilij.li", RedirectStatus: tt.status, MaxRequests: 1})
+			require.Equal(t, tt.wantErr, err)
+			if err != nil {
+				return
+			}
+			r, err := s.Redirect(id, nil)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, r.Status)
+			// the exhausted redirects are temporary
+			s.Redirect(id, nil)
+			r, err = s.Redirect(id, nil)
+			require.Equal(t, ErrURLExhausted, err)
+			require.Equal(t, http.StatusFound, r.Status)
+		})
+	}
+
+	id, err := s.UpsertURLSimple(&URLReq{URL: "https://ilij.li", RedirectStatus: http.StatusMovedPermanently})
+	require.NoError(t, err)
+	status := http.StatusOK
+	_, err = s.PatchURL(id, &URLPatch{RedirectStatus: &status}, "")
+	require.Equal(t, ErrInvalidRedirectStatus, err)
+	status = 0
+	u, err := s.PatchURL(id, &URLPatch{RedirectStatus: &status}, "")
+	require.NoError(t, err)
+	require.Equal(t, uint32(0), u.RedirectStatus)
+	r, err := s.Redirect(id, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusTemporaryRedirect, r.Status)
+}
@@ -0,0 +1,137 @@
+package urlstore
+
+import (
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	maxminddb "github.com/oschwald/maxminddb-golang"
+)
+
+// countryCode matches an ISO 3166-1 alpha-2 country code
+var countryCode = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// GeoLocator resolves the location of an ip address
+type GeoLocator interface {
+	// Locate returns the ISO country code and the english
+	// city name of an ip, empty if they are not known
+	Locate(ip net.IP) (country, city string, err error)
+	// Close releases the locator
+	Close() error
+}
+
+// mmdbLocator is a GeoLocator using a MaxMind database
+type mmdbLocator struct {
+	db *maxminddb.Reader
+}
+
+// mmdbRecord are the fields read from a MaxMind country or city database
+type mmdbRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+}
+
+// NewMMDBLocator opens a MaxMind (.mmdb) country or city database
+func NewMMDBLocator(path string) (GeoLocator, error) {
+	db, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &mmdbLocator{db: db}, nil
+}
+
+func (l *mmdbLocator) Locate(ip net.IP) (country, city string, err error) {
+	r := mmdbRecord{}
+	if err = l.db.Lookup(ip, &r); err != nil {
+		return
+	}
+	return r.Country.ISOCode, r.City.Names["en"], nil
+}
+
+func (l *mmdbLocator) Close() error {
+	return l.db.Close()
+}
+
+// openGeoLocator opens the geo database in the configuration,
+// it returns nil if geo is not configured
+func openGeoLocator(cfg ConfigSchema) (GeoLocator, error) {
+	if empty(cfg.Stats.GeoDBPath) {
+		return nil, nil
+	}
+	return NewMMDBLocator(cfg.Stats.GeoDBPath)
+}
+
+// locate returns the country and the city (if enabled) of a visit
+func (s *Store) locate(v *Visit) (country, city string) {
+	country, city = breakdownUnknown, breakdownUnknown
+	ip := net.ParseIP(v.IP)
+	if ip == nil {
+		return
+	}
+	c, ct, err := s.geo.Locate(ip)
+	if err != nil {
+		return
+	}
+	if len(c) > 0 {
+		country = c
+	}
+	if len(ct) > 0 {
+		city = c + "/" + ct
+	}
+	return
+}
+
+// Geo returns the countries and the cities (if enabled) with most clicks
+// of the url id, it returns nil if geo is not configured
+func (s *Store) Geo(id string, top int) (g *GeoStats, err error) {
+	if s.geo == nil {
+		return
+	}
+	b, err := s.Breakdown(id, top)
+	if err != nil {
+		return
+	}
+	g = &GeoStats{Countries: b[BreakdownCountry]}
+	if s.Config.Stats.GeoCity {
+		g.Cities = b[BreakdownCity]
+	}
+	return
+}
+
+// countryList converts a map of country code to url in a list
+// sorted by code, validating the codes and the urls
+func countryList(countries map[string]string) (l []*Country, err error) {
+	for code, target := range countries {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if !countryCode.MatchString(code) || len(strings.TrimSpace(target)) == 0 {
+			return nil, ErrInvalidCountry
+		}
+		if _, err = url.Parse(target); err != nil {
+			return nil, err
+		}
+		l = append(l, &Country{Code: code, URL: target})
+	}
+	sort.Slice(l, func(i, j int) bool { return l[i].Code < l[j].Code })
+	return
+}
+
+// matchCountry returns the target for the country of a visit,
+// nothing if geo is not configured or the country is not known
+func (s *Store) matchCountry(countries []*Country, v *Visit) (target string, ok bool) {
+	if s.geo == nil {
+		return
+	}
+	country, _ := s.locate(v)
+	for _, c := range countries {
+		if c.Code == country {
+			return c.URL, true
+		}
+	}
+	return
+}
@@ -0,0 +1,149 @@
+package urlstore
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testLocator is a GeoLocator with fixed locations
+type testLocator map[string][2]string
+
+func (l testLocator) Locate(ip net.IP) (country, city string, err error) {
+	loc, ok := l[ip.String()]
+	if !ok {
+		return "", "", fmt.Errorf("not found")
+	}
+	return loc[0], loc[1], nil
+}
+
+func (l testLocator) Close() error {
+	return nil
+}
+
+func TestGeo(t *testing.T) {
+	t.Parallel()
+	locator := testLocator{
+		"10.0.0.1": {"IT", "Milan"},
+		"10.0.0.2": {"IT", "Rome"},
+		"10.0.0.3": {"DE", ""},
+	}
+	ips := []string{"10.0.0.1", "10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.9", "not an ip"}
+
+	tests := []struct {
+		name    string
+		geo     GeoLocator
+		city    bool
+		wantGeo *GeoStats
+	}{
+		{"disabled", nil, false, nil},
+		{"country", locator, false, &GeoStats{
+			Countries: []BreakdownEntry{{"IT", 3}, {"unknown", 2}, {"DE", 1}},
+		}},
+		{"city", locator, true, &GeoStats{
+			Countries: []BreakdownEntry{{"IT", 3}, {"unknown", 2}, {"DE", 1}},
+			Cities:    []BreakdownEntry{{"unknown", 3}, {"IT/Milan", 2}, {"IT/Rome", 1}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := buildConifgTest()
+			cfg.Stats.GeoCity = tt.city
+			s := openBackendStore(t, BackendMemory, cfg)
+			defer s.Close()
+			s.geo = tt.geo
+			id, err := s.UpsertURLSimple(&URLReq{URL: "https://ilij.li"})
+			require.NoError(t, err)
+			for _, ip := range ips {
+				_, err = s.GetURLRedirectVisit(id, &Visit{IP: ip})
+				require.NoError(t, err)
+			}
+			g, err := s.Geo(id, 10)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantGeo, g)
+			b, err := s.Breakdown(id, 10)
+			require.NoError(t, err)
+			_, hasCountry := b[BreakdownCountry]
+			require.Equal(t, tt.geo != nil, hasCountry)
+		})
+	}
+}
+
+func TestCountries(t *testing.T) {
+	t.Parallel()
+	s := openBackendStore(t, BackendMemory, buildConifgTest())
+	defer s.Close()
+	id, err := s.UpsertURLSimple(&URLReq{
+		URL:       "https://ilij.li",
+		Countries: map[string]string{"it": "https://ilij.li/eu", "DE": "https://ilij.li/eu", "US": "https://ilij.li/us"},
+		Languages: map[string]string{"fr": "https://ilij.li/fr"},
+	})
+	require.NoError(t, err)
+	u, err := s.GetURLInfo(id)
+	require.NoError(t, err)
+	require.Equal(t, []*Country{
+		{Code: "DE", URL: "https://ilij.li/eu"},
+		{Code: "IT", URL: "https://ilij.li/eu"},
+		{Code: "US", URL: "https://ilij.li/us"},
+	}, u.Countries)
+
+	// without geo the countries are ignored
+	got, err := s.GetURLRedirectVisit(id, &Visit{IP: "10.0.0.1"})
+	require.NoError(t, err)
+	require.Equal(t, "https://ilij.li", got)
+
+	s.geo = testLocator{"10.0.0.1": {"IT", "Milan"}, "10.0.0.2": {"US", ""}, "10.0.0.3": {"FR", ""}}
+	tests := []struct {
+		name           string
+		ip             string
+		acceptLanguage string
+		want           string
+	}{
+		{"country", "10.0.0.1", "", "https://ilij.li/eu"},
+		{"other country", "10.0.0.2", "", "https://ilij.li/us"},
+		{"before the language", "10.0.0.2", "fr", "https://ilij.li/us"},
+		{"no country", "10.0.0.3", "", "https://ilij.li"},
+		{"no country language", "10.0.0.3", "fr", "https://ilij.li/fr"},
+		{"unresolved", "10.0.0.9", "", "https://ilij.li"},
+		{"invalid ip", "", "", "https://ilij.li"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.GetURLRedirectVisit(id, &Visit{IP: tt.ip, AcceptLanguage: tt.acceptLanguage})
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+
+	// the countries are replaced by a patch
+	u, err = s.PatchURL(id, &URLPatch{Countries: map[string]string{"us": "https://ilij.li/us2"}}, "")
+	require.NoError(t, err)
+	require.Equal(t, []*Country{{Code: "US", URL: "https://ilij.li/us2"}}, u.Countries)
+
+	// invalid countries
+	for _, c := range []map[string]string{{"": "https://ilij.li"}, {"ITA": "https://ilij.li"}, {"IT": ""}} {
+		_, err = s.UpsertURLSimple(&URLReq{URL: "https://ilij.li", Countries: c})
+		require.Equal(t, ErrInvalidCountry, err)
+	}
+
+	// csv
+	req := &URLReq{}
+	require.NoError(t, req.UnmarshalRecord([]string{"https://ilij.li", "", "", "", "", "", "", "", "IT:https://ilij.li/it"}))
+	require.Equal(t, map[string]string{"IT": "https://ilij.li/it"}, req.Countries)
+	record := u.MarshalRecord()
+	require.Equal(t, "US:https://ilij.li/us2", record[12])
+	ur := &URLInfo{}
+	require.NoError(t, ur.UnmarshalRecord(record))
+	require.Equal(t, u.Countries, ur.Countries)
+}
+
+func TestGeoDBNotFound(t *testing.T) {
+	t.Parallel()
+	cfg := buildConifgTest()
+	cfg.Server.DbBackend = BackendMemory
+	cfg.Stats.GeoDBPath = "/notexisting/geo.mmdb"
+	_, err := NewStore(cfg)
+	require.Error(t, err)
+}
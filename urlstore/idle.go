@@ -0,0 +1,26 @@
+package urlstore
+
+import "time"
+
+// IdleExpireOn returns the time the url expires if it is not
+// redirected in the meantime, zero if the url has no idle ttl
+func (u URLInfo) IdleExpireOn() time.Time {
+	if u.IdleTTL == 0 {
+		return time.Time{}
+	}
+	last := u.BountAt
+	if u.LastVisit.After(last) {
+		last = u.LastVisit
+	}
+	return last.Add(time.Duration(u.IdleTTL) * time.Second)
+}
+
+// Expired tells if the url is expired at the time now,
+// by its expiration date or by its idle ttl
+func (u URLInfo) Expired(now time.Time) bool {
+	if !u.ExpireOn.IsZero() && now.After(u.ExpireOn) {
+		return true
+	}
+	idle := u.IdleExpireOn()
+	return !idle.IsZero() && now.After(idle)
+}
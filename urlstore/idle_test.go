@@ -0,0 +1,78 @@
+package urlstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestURLInfo_Expired(t *testing.T) {
+	t0 := time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		u    URLInfo
+		now  time.Time
+		want bool
+	}{
+		{"no expiration", URLInfo{BountAt: t0}, t0.Add(time.Hour), false},
+		{"expire on", URLInfo{BountAt: t0, ExpireOn: t0.Add(time.Minute)}, t0.Add(time.Hour), true},
+		{"idle never visited", URLInfo{BountAt: t0, IdleTTL: 60}, t0.Add(time.Hour), true},
+		{"idle visited", URLInfo{BountAt: t0, IdleTTL: 60, LastVisit: t0.Add(59 * time.Minute)}, t0.Add(time.Hour), false},
+		{"idle expired", URLInfo{BountAt: t0, IdleTTL: 60, LastVisit: t0.Add(58 * time.Minute)}, t0.Add(time.Hour), true},
+		{"idle expire on", URLInfo{BountAt: t0, IdleTTL: 3600, ExpireOn: t0.Add(time.Minute), LastVisit: t0.Add(59 * time.Minute)}, t0.Add(time.Hour), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.u.Expired(tt.now))
+		})
+	}
+}
+
+func TestIdleTTL(t *testing.T) {
+	t.Parallel()
+	cfg := buildConifgTest()
+	cfg.ShortID.IdleTTL = 3600
+	s := openBackendStore(t, BackendMemory, cfg)
+	defer s.Close()
+
+	// the global idle ttl
+	id, err := s.UpsertURLSimple(&URLReq{URL: "https://ilij.li/a"})
+	require.NoError(t, err)
+	u, _ := s.Peek(id)
+	require.Equal(t, uint64(3600), u.IdleTTL)
+
+	// the local idle ttl has priority
+	boundAt := time.Now().Add(-time.Hour)
+	id, err = s.UpsertURL(&URLReq{URL: "https://ilij.li/b", IdleTTL: 60, ExpiredURL: "https://ilij.li/expired"}, false, false, boundAt)
+	require.NoError(t, err)
+	u, _ = s.Peek(id)
+	require.Equal(t, uint64(60), u.IdleTTL)
+	require.Equal(t, URLStateExpired, u.State(time.Now()))
+	r, err := s.Redirect(id, nil)
+	require.Equal(t, ErrURLExpired, err)
+	require.Equal(t, "https://ilij.li/expired", r.URL)
+	// an expired url is not extended by the redirects
+	u, _ = s.Peek(id)
+	require.True(t, u.LastVisit.IsZero())
+
+	// every redirect extends the expiration
+	ttl := uint64(7200)
+	_, err = s.PatchURL(id, &URLPatch{IdleTTL: &ttl}, "")
+	require.NoError(t, err)
+	u, _ = s.Peek(id)
+	etag := u.ETag()
+	r, err = s.Redirect(id, nil)
+	require.NoError(t, err)
+	require.Equal(t, "https://ilij.li/b", r.URL)
+	u, _ = s.Peek(id)
+	require.False(t, u.LastVisit.IsZero())
+	require.True(t, u.IdleExpireOn().After(time.Now().Add(time.Hour)))
+	require.Equal(t, etag, u.ETag())
+
+	// the last visit is persisted
+	require.NoError(t, s.Flush())
+	s.uc.Purge()
+	got, _ := s.Peek(id)
+	require.True(t, u.LastVisit.Equal(got.LastVisit))
+}
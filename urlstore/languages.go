@@ -0,0 +1,85 @@
+package urlstore
+
+import (
+	net "net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// languageTag matches a well formed language tag (BCP 47)
+var languageTag = regexp.MustCompile(`^[A-Za-z]{1,8}(-[A-Za-z0-9]{1,8})*$`)
+
+// languageList converts a map of language tag to url
+// in a list sorted by tag, validating the tags and the urls
+func languageList(languages map[string]string) (l []*Language, err error) {
+	for tag, target := range languages {
+		tag = strings.TrimSpace(tag)
+		if !languageTag.MatchString(tag) || len(strings.TrimSpace(target)) == 0 {
+			return nil, ErrInvalidLanguage
+		}
+		if _, err = net.Parse(target); err != nil {
+			return nil, err
+		}
+		l = append(l, &Language{Tag: tag, URL: target})
+	}
+	sort.Slice(l, func(i, j int) bool { return l[i].Tag < l[j].Tag })
+	return
+}
+
+// acceptedLanguages returns the language tags of an Accept-Language
+// header sorted by quality, the wildcard and the tags with q=0 are skipped
+func acceptedLanguages(acceptLanguage string) (tags []string) {
+	quality := make(map[string]float64)
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		pieces := strings.Split(part, ";")
+		tag := strings.TrimSpace(pieces[0])
+		if len(tag) == 0 || tag == "*" {
+			continue
+		}
+		q := 1.0
+		for _, p := range pieces[1:] {
+			p = strings.TrimSpace(p)
+			if strings.HasPrefix(p, "q=") {
+				if v, err := strconv.ParseFloat(p[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if _, seen := quality[tag]; q <= 0 || seen {
+			continue
+		}
+		quality[tag] = q
+		tags = append(tags, tag)
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return quality[tags[i]] > quality[tags[j]] })
+	return
+}
+
+// matchLanguage returns the target for the preferred language of an
+// Accept-Language header. For each language, by quality, the tag is
+// looked up removing the subtags from the end (pt-BR then pt), then a
+// tag with more subtags is accepted (pt matches pt-BR)
+func matchLanguage(languages []*Language, acceptLanguage string) (target string, ok bool) {
+	for _, tag := range acceptedLanguages(acceptLanguage) {
+		for prefix := tag; len(prefix) > 0; {
+			for _, l := range languages {
+				if strings.EqualFold(l.Tag, prefix) {
+					return l.URL, true
+				}
+			}
+			i := strings.LastIndex(prefix, "-")
+			if i < 0 {
+				break
+			}
+			prefix = prefix[:i]
+		}
+		for _, l := range languages {
+			if len(l.Tag) > len(tag) && strings.EqualFold(l.Tag[:len(tag)+1], tag+"-") {
+				return l.URL, true
+			}
+		}
+	}
+	return
+}
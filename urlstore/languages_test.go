@@ -0,0 +1,86 @@
+package urlstore
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_matchLanguage(t *testing.T) {
+	languages := []*Language{
+		{Tag: "de", URL: "https://ex.com/de"},
+		{Tag: "fr", URL: "https://ex.com/fr"},
+		{Tag: "pt-BR", URL: "https://ex.com/pt-br"},
+		{Tag: "zh-Hant-TW", URL: "https://ex.com/zh-tw"},
+	}
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		want           string
+	}{
+		{"empty", "", ""},
+		{"any", "*", ""},
+		{"no match", "en-US,en;q=0.9", ""},
+		{"exact", "fr", "https://ex.com/fr"},
+		{"case", "PT-br", "https://ex.com/pt-br"},
+		{"subtag removed", "de-CH", "https://ex.com/de"},
+		{"subtag added", "pt", "https://ex.com/pt-br"},
+		{"subtags removed", "zh-Hant-TW-x-private", "https://ex.com/zh-tw"},
+		{"quality", "de;q=0.5,fr;q=0.8,en", "https://ex.com/fr"},
+		{"order", "en,de,fr", "https://ex.com/de"},
+		{"refused", "de;q=0,it", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := matchLanguage(languages, tt.acceptLanguage)
+			require.Equal(t, tt.want, got)
+			require.Equal(t, len(tt.want) > 0, ok)
+		})
+	}
+}
+
+func TestLanguages(t *testing.T) {
+	t.Parallel()
+	s := openBackendStore(t, BackendMemory, buildConifgTest())
+	defer s.Close()
+	req := &URLReq{}
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"url": "https://ilij.li",
+		"languages": {"de": "https://ilij.li/de", "pt-BR": "https://ilij.li/pt"}
+	}`), req))
+	id, err := s.UpsertURLSimple(req)
+	require.NoError(t, err)
+	u, err := s.GetURLInfo(id)
+	require.NoError(t, err)
+	require.Equal(t, []*Language{{Tag: "de", URL: "https://ilij.li/de"}, {Tag: "pt-BR", URL: "https://ilij.li/pt"}}, u.Languages)
+
+	got, err := s.GetURLRedirectVisit(id, &Visit{AcceptLanguage: "pt-PT,pt;q=0.9,de;q=0.5"})
+	require.NoError(t, err)
+	require.Equal(t, "https://ilij.li/pt", got)
+	got, err = s.GetURLRedirectVisit(id, &Visit{AcceptLanguage: "en"})
+	require.NoError(t, err)
+	require.Equal(t, "https://ilij.li", got)
+
+	// the languages are replaced by a patch
+	u, err = s.PatchURL(id, &URLPatch{Languages: map[string]string{"fr": "https://ilij.li/fr"}}, "")
+	require.NoError(t, err)
+	require.Equal(t, []*Language{{Tag: "fr", URL: "https://ilij.li/fr"}}, u.Languages)
+	u, err = s.PatchURL(id, &URLPatch{}, "")
+	require.NoError(t, err)
+	require.Len(t, u.Languages, 1)
+	u, err = s.PatchURL(id, &URLPatch{Languages: map[string]string{}}, "")
+	require.NoError(t, err)
+	require.Empty(t, u.Languages)
+
+	// invalid languages
+	for _, l := range []map[string]string{{"": "https://ilij.li"}, {"en_US": "https://ilij.li"}, {"en": " "}} {
+		_, err = s.UpsertURLSimple(&URLReq{URL: "https://ilij.li", Languages: l})
+		require.Equal(t, ErrInvalidLanguage, err)
+	}
+
+	// csv import
+	req = &URLReq{}
+	require.NoError(t, req.UnmarshalRecord([]string{"https://ilij.li", "", "", "", "", "", "", "de:https://ilij.li/de it:https://ilij.li/it"}))
+	require.Equal(t, map[string]string{"de": "https://ilij.li/de", "it": "https://ilij.li/it"}, req.Languages)
+}
@@ -0,0 +1,262 @@
+package urlstore
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// memoryBackend is a Backend that keeps the data in memory,
+// update transactions are serialized
+type memoryBackend struct {
+	mu   sync.RWMutex
+	data map[string][]byte
+}
+
+// NewMemoryBackend creates an empty in memory backend
+func NewMemoryBackend() Backend {
+	return &memoryBackend{data: make(map[string][]byte)}
+}
+
+func (m *memoryBackend) View(fn func(txn Txn) error) error {
+	txn := m.NewTransaction(false)
+	defer txn.Discard()
+	return fn(txn)
+}
+
+func (m *memoryBackend) Update(fn func(txn Txn) error) (err error) {
+	txn := m.NewTransaction(true)
+	defer txn.Discard()
+	if err = fn(txn); err != nil {
+		return
+	}
+	return txn.Commit()
+}
+
+func (m *memoryBackend) NewTransaction(update bool) Txn {
+	t := &memoryTxn{m: m, update: update}
+	if update {
+		m.mu.Lock()
+		t.writes = make(map[string]*memoryWrite)
+	}
+	return t
+}
+
+// Backup writes the key/values as a sequence of
+// length prefixed keys and values
+func (m *memoryBackend) Backup(w io.Writer) (err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	bw := bufio.NewWriter(w)
+	buf := make([]byte, binary.MaxVarintLen64)
+	for _, k := range m.keys("") {
+		for _, p := range [][]byte{[]byte(k), m.data[k]} {
+			n := binary.PutUvarint(buf, uint64(len(p)))
+			if _, err = bw.Write(buf[:n]); err != nil {
+				return
+			}
+			if _, err = bw.Write(p); err != nil {
+				return
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// Load reads a dump created with Backup
+func (m *memoryBackend) Load(r io.Reader) (err error) {
+	br := bufio.NewReader(r)
+	read := func() (p []byte, err error) {
+		l, err := binary.ReadUvarint(br)
+		if err != nil {
+			return
+		}
+		p = make([]byte, l)
+		_, err = io.ReadFull(br, p)
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for {
+		k, err := read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		v, err := read()
+		if err != nil {
+			return err
+		}
+		m.data[string(k)] = v
+	}
+}
+
+func (m *memoryBackend) RunGC(discardRatio float64) error {
+	return nil
+}
+
+// Size returns the total size of the keys and values
+func (m *memoryBackend) Size() (lsm, vlog int64) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for k, v := range m.data {
+		lsm += int64(len(k) + len(v))
+	}
+	return
+}
+
+func (m *memoryBackend) Close() error {
+	return nil
+}
+
+// keys returns the sorted keys with a prefix, the lock must be held
+func (m *memoryBackend) keys(prefix string) (keys []string) {
+	for k := range m.data {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return
+}
+
+// memoryWrite is a pending write in a transaction
+type memoryWrite struct {
+	value   []byte
+	deleted bool
+}
+
+// memoryTxn is a transaction on the memory backend,
+// an update transaction holds the backend lock until commit or discard
+type memoryTxn struct {
+	m      *memoryBackend
+	update bool
+	done   bool
+	writes map[string]*memoryWrite
+}
+
+func (t *memoryTxn) Get(k []byte) (v []byte, err error) {
+	if t.update {
+		if w, ok := t.writes[string(k)]; ok {
+			if w.deleted {
+				return nil, ErrKeyNotFound
+			}
+			return append([]byte{}, w.value...), nil
+		}
+	} else {
+		t.m.mu.RLock()
+		defer t.m.mu.RUnlock()
+	}
+	val, ok := t.m.data[string(k)]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return append([]byte{}, val...), nil
+}
+
+func (t *memoryTxn) Set(k, v []byte) error {
+	if !t.update {
+		return ErrReadOnlyTxn
+	}
+	t.writes[string(k)] = &memoryWrite{value: append([]byte{}, v...)}
+	return nil
+}
+
+func (t *memoryTxn) Delete(k []byte) error {
+	if !t.update {
+		return ErrReadOnlyTxn
+	}
+	t.writes[string(k)] = &memoryWrite{deleted: true}
+	return nil
+}
+
+// Iterate takes a snapshot of the keys and values with the prefix
+func (t *memoryTxn) Iterate(prefix []byte) Iterator {
+	if !t.update {
+		t.m.mu.RLock()
+		defer t.m.mu.RUnlock()
+	}
+	p := string(prefix)
+	values := make(map[string][]byte)
+	for _, k := range t.m.keys(p) {
+		values[k] = t.m.data[k]
+	}
+	for k, w := range t.writes {
+		if !strings.HasPrefix(k, p) {
+			continue
+		}
+		if w.deleted {
+			delete(values, k)
+			continue
+		}
+		values[k] = w.value
+	}
+	it := &memoryIterator{pos: -1}
+	for k := range values {
+		it.keys = append(it.keys, k)
+	}
+	sort.Strings(it.keys)
+	for _, k := range it.keys {
+		it.values = append(it.values, append([]byte{}, values[k]...))
+	}
+	return it
+}
+
+func (t *memoryTxn) Commit() error {
+	if t.done || !t.update {
+		return nil
+	}
+	for k, w := range t.writes {
+		if w.deleted {
+			delete(t.m.data, k)
+			continue
+		}
+		t.m.data[k] = w.value
+	}
+	t.done = true
+	t.m.mu.Unlock()
+	return nil
+}
+
+func (t *memoryTxn) Discard() {
+	if t.done {
+		return
+	}
+	t.done = true
+	if t.update {
+		t.m.mu.Unlock()
+	}
+}
+
+// memoryIterator iterates over a snapshot of keys
+type memoryIterator struct {
+	keys   []string
+	values [][]byte
+	pos    int
+}
+
+func (i *memoryIterator) Next() bool {
+	i.pos++
+	return i.pos < len(i.keys)
+}
+
+func (i *memoryIterator) Seek(k []byte) {
+	i.pos = sort.SearchStrings(i.keys, string(k)) - 1
+}
+
+func (i *memoryIterator) Key() []byte {
+	return []byte(i.keys[i.pos])
+}
+
+func (i *memoryIterator) Value() ([]byte, error) {
+	return i.values[i.pos], nil
+}
+
+func (i *memoryIterator) Close() {
+	i.keys, i.values = nil, nil
+}
@@ -0,0 +1,94 @@
+package urlstore
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricsNamespace = "distill"
+
+// Redirect outcomes used as label for the redirect metrics
+const (
+	RedirectOK        = "ok"
+	RedirectExpired   = "expired"
+	RedirectExhausted = "exhausted"
+	RedirectNotFound  = "not_found"
+	RedirectInactive  = "inactive"
+	RedirectLimited   = "rate_limited"
+)
+
+// storeMetrics are the prometheus collectors of a store
+type storeMetrics struct {
+	redirects   *prometheus.CounterVec
+	upserts     prometheus.Counter
+	deletes     prometheus.Counter
+	cacheHits   prometheus.Counter
+	cacheMisses prometheus.Counter
+	gcRuns      prometheus.Counter
+}
+
+// newStoreMetrics creates the store collectors and registers them in reg,
+// the database size is read from db at every scrape
+func newStoreMetrics(reg prometheus.Registerer, db Backend) *storeMetrics {
+	m := &storeMetrics{
+		redirects: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "redirects_total",
+			Help:      "Number of redirect requests by outcome.",
+		}, []string{"outcome"}),
+		upserts: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "upserts_total",
+			Help:      "Number of urls inserted or updated.",
+		}),
+		deletes: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "deletes_total",
+			Help:      "Number of urls deleted.",
+		}),
+		cacheHits: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "url_cache_hits_total",
+			Help:      "Number of url lookups served by the cache.",
+		}),
+		cacheMisses: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "url_cache_misses_total",
+			Help:      "Number of url lookups served by the database.",
+		}),
+		gcRuns: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "db_gc_runs_total",
+			Help:      "Number of database garbage collections.",
+		}),
+	}
+	// the outcomes are always exported, also when zero
+	for _, o := range []string{RedirectOK, RedirectExpired, RedirectExhausted, RedirectNotFound, RedirectInactive, RedirectLimited} {
+		m.redirects.WithLabelValues(o)
+	}
+	reg.MustRegister(m.redirects, m.upserts, m.deletes, m.cacheHits, m.cacheMisses, m.gcRuns)
+	reg.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "db_lsm_size_bytes",
+			Help:      "Size of the database LSM tree.",
+		}, func() float64 {
+			lsm, _ := db.Size()
+			return float64(lsm)
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "db_vlog_size_bytes",
+			Help:      "Size of the database value log.",
+		}, func() float64 {
+			_, vlog := db.Size()
+			return float64(vlog)
+		}),
+	)
+	return m
+}
+
+// Registry returns the prometheus registry with the store metrics,
+// other collectors can be registered in it
+func (s *Store) Registry() *prometheus.Registry {
+	return s.registry
+}
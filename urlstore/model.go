@@ -1,10 +1,15 @@
 package urlstore
 
 import (
+	"crypto/sha1"
 	"encoding/binary"
+	"encoding/hex"
 	"fmt"
 	"net/http"
+	"net/url"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -14,12 +19,16 @@ const (
 	opcodeDelete  = 2
 	opcodeExpired = 3
 	opcodeStore   = 4
+	opcodeUpdate  = 5
 )
 
 const (
-	keySysPrefix  = 0x00
-	keyStatPrefix = 0x02
-	keyURLPrefix  = 0x04
+	keySysPrefix     = 0x00
+	keyStatPrefix    = 0x02
+	keyClicksPrefix  = 0x03
+	keyURLPrefix     = 0x04
+	keyArchivePrefix = 0x05
+	keyTrashPrefix   = 0x06
 )
 
 var (
@@ -50,15 +59,110 @@ type ShortID struct {
 	ID string `json:"id"`
 }
 
+// ShortIDs is the reply of a batch of urls
+type ShortIDs struct {
+	IDs []string `json:"ids"`
+}
+
+// BatchMaxCount is the max number of urls created in a batch
+const BatchMaxCount = 1000
+
 // URLReq request from a client to register an url
 type URLReq struct {
-	ID           string    `json:"id"`
-	URL          string    `json:"url"`
-	MaxRequests  uint64    `json:"max_requests,omitempty"`
-	ExhaustedURL string    `json:"url_exhausted"`
-	TTL          uint64    `json:"ttl,omitempty"`
-	ExpireOn     time.Time `json:"expire_on,omitempty"`
-	ExpiredURL   string    `json:"url_expired"`
+	ID           string     `json:"id"`
+	URL          string     `json:"url"`
+	MaxRequests  uint64     `json:"max_requests,omitempty"`
+	ExhaustedURL string     `json:"url_exhausted"`
+	TTL          uint64     `json:"ttl,omitempty"`
+	ExpireOn     time.Time  `json:"expire_on,omitempty"`
+	ExpiredURL   string     `json:"url_expired"`
+	Variants     []*Variant `json:"variants,omitempty"`
+	Rules        []*Rule    `json:"rules,omitempty"`
+	// Languages maps the language tags to the targets
+	Languages map[string]string `json:"languages,omitempty"`
+	// Countries maps the country codes to the targets
+	Countries map[string]string `json:"countries,omitempty"`
+	// RedirectStatus is the http status of the redirect,
+	// 0 uses the one in the configuration
+	RedirectStatus int `json:"redirect_status,omitempty"`
+	// ForwardQuery and ForwardPath forward the query string and the
+	// trailing path of the request, nil uses the configuration
+	ForwardQuery *bool `json:"forward_query,omitempty"`
+	ForwardPath  *bool `json:"forward_path,omitempty"`
+	// UTMParams are added to the targets, overriding the ones
+	// of the Campaign preset in the configuration
+	UTMParams
+	Campaign string `json:"campaign,omitempty"`
+	// Password protects the url, only its hash is stored
+	Password string `json:"password,omitempty"`
+	// OneTime urls expire after the first redirect
+	OneTime bool `json:"one_time,omitempty"`
+	// ActiveFrom is the date the url becomes active, before
+	// that the requests are redirected to the InactiveURL
+	ActiveFrom  time.Time `json:"active_from,omitempty"`
+	InactiveURL string    `json:"url_inactive,omitempty"`
+	// Schedule are the changes of the url at a given time
+	Schedule []*Change `json:"schedule,omitempty"`
+	// IdleTTL expires the url after the seconds without redirects
+	IdleTTL uint64 `json:"idle_ttl,omitempty"`
+	// WindowMaxRequests are the max redirects every WindowSize seconds
+	WindowMaxRequests uint64 `json:"window_max_requests,omitempty"`
+	WindowSize        uint64 `json:"window_size,omitempty"`
+}
+
+// URLPatch is a partial update of an url, nil fields are left unchanged
+type URLPatch struct {
+	URL          *string     `json:"url,omitempty"`
+	MaxRequests  *uint64     `json:"max_requests,omitempty"`
+	ExhaustedURL *string     `json:"url_exhausted,omitempty"`
+	TTL          *uint64     `json:"ttl,omitempty"`
+	ExpireOn     *time.Time  `json:"expire_on,omitempty"`
+	ExpiredURL   *string     `json:"url_expired,omitempty"`
+	Variants     *[]*Variant `json:"variants,omitempty"`
+	Rules        *[]*Rule    `json:"rules,omitempty"`
+	// Languages and Countries replace the language and
+	// country targets if not nil, an empty map removes them
+	Languages      map[string]string `json:"languages,omitempty"`
+	Countries      map[string]string `json:"countries,omitempty"`
+	RedirectStatus *int              `json:"redirect_status,omitempty"`
+	ForwardQuery   *bool             `json:"forward_query,omitempty"`
+	ForwardPath    *bool             `json:"forward_path,omitempty"`
+	// UTMParams replace all the utm parameters if any is set
+	*UTMParams
+	Campaign *string `json:"campaign,omitempty"`
+	// Password replaces the password, empty removes it
+	Password *string `json:"password,omitempty"`
+	OneTime  *bool   `json:"one_time,omitempty"`
+	// ActiveFrom is removed with the zero time
+	ActiveFrom  *time.Time `json:"active_from,omitempty"`
+	InactiveURL *string    `json:"url_inactive,omitempty"`
+	Schedule    *[]*Change `json:"schedule,omitempty"`
+	IdleTTL     *uint64    `json:"idle_ttl,omitempty"`
+	// WindowMaxRequests and WindowSize do not reset the current window
+	WindowMaxRequests *uint64 `json:"window_max_requests,omitempty"`
+	WindowSize        *uint64 `json:"window_size,omitempty"`
+}
+
+// URL states used to filter the urls
+const (
+	URLStateActive    = "active"
+	URLStateExpired   = "expired"
+	URLStateExhausted = "exhausted"
+	URLStateInactive  = "inactive"
+)
+
+// URLFilter selects the urls in a listing, empty fields match all the urls
+type URLFilter struct {
+	URL         string
+	State       string
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+}
+
+// URLList is a page of urls, Next is the cursor for the next page
+type URLList struct {
+	URLs []*URLInfo `json:"urls"`
+	Next string     `json:"next,omitempty"`
 }
 
 // Statistics contains the global statistics
@@ -69,6 +173,140 @@ type Statistics struct {
 	Upserts     uint64    `json:"upserts"`
 	Deletes     uint64    `json:"deletes"`
 	LastRequest time.Time `json:"last_request"`
+	Uniques     uint64    `json:"uniques"`
+}
+
+// Granularities of the clicks time series
+const (
+	GranularityHour = "hour"
+	GranularityDay  = "day"
+)
+
+// ClickBucket is the number of clicks in the bucket starting at Time
+type ClickBucket struct {
+	Time   time.Time `json:"time"`
+	Clicks uint64    `json:"clicks"`
+}
+
+// ClickSeries are the clicks of an url in a time range,
+// only the buckets with clicks are included
+type ClickSeries struct {
+	Granularity string        `json:"granularity"`
+	From        time.Time     `json:"from"`
+	To          time.Time     `json:"to"`
+	Total       uint64        `json:"total"`
+	Buckets     []ClickBucket `json:"buckets"`
+}
+
+// Visit describes the client requesting a short url
+type Visit struct {
+	IP             string
+	Referrer       string
+	UserAgent      string
+	AcceptLanguage string
+	// Variant is the key of the variant the client has been redirected
+	// to before (from the sticky cookie), empty for a new client
+	Variant string
+	// Path is the escaped path after the short id and
+	// Query the raw query string of the request
+	Path  string
+	Query string
+	// Password is the password submitted for a protected url
+	Password string
+}
+
+// RedirectStatuses are the http status codes allowed for the redirects
+var RedirectStatuses = []int{
+	http.StatusMovedPermanently,
+	http.StatusFound,
+	http.StatusSeeOther,
+	http.StatusTemporaryRedirect,
+	http.StatusPermanentRedirect,
+}
+
+// ValidRedirectStatus tells if the status is allowed for the redirects
+func ValidRedirectStatus(status int) bool {
+	for _, s := range RedirectStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// PermanentRedirectStatus tells if the status is a permanent redirect
+func PermanentRedirectStatus(status int) bool {
+	return status == http.StatusMovedPermanently || status == http.StatusPermanentRedirect
+}
+
+// Redirect is the target of a short url for a visit
+type Redirect struct {
+	URL string
+	// Status is the http status of the redirect
+	Status int
+	// Variant is the key of the variant chosen for the visit,
+	// empty if the url has no variants
+	Variant string
+}
+
+// Dimensions of the clicks breakdown
+const (
+	BreakdownReferrer = "referrer"
+	BreakdownBrowser  = "browser"
+	BreakdownOS       = "os"
+	BreakdownDevice   = "device"
+	BreakdownBot      = "bot"
+	BreakdownLanguage = "language"
+	BreakdownCountry  = "country"
+	BreakdownCity     = "city"
+	BreakdownVariant  = "variant"
+)
+
+// BreakdownEntry is the number of clicks for a value of a dimension
+type BreakdownEntry struct {
+	Value  string `json:"value"`
+	Clicks uint64 `json:"clicks"`
+}
+
+// Breakdown are the values with most clicks for each dimension
+type Breakdown map[string][]BreakdownEntry
+
+// GeoStats are the countries and the cities with most clicks,
+// cities are in the form country/city
+type GeoStats struct {
+	Countries []BreakdownEntry `json:"countries"`
+	Cities    []BreakdownEntry `json:"cities,omitempty"`
+}
+
+// Key returns a stable identifier of the variant, derived from its url,
+// it does not change when the variants of the url are reordered
+func (v *Variant) Key() string {
+	h := sha1.Sum([]byte(v.URL))
+	return hex.EncodeToString(h[:6])
+}
+
+// VariantStats is the number of redirects to a variant of an url
+type VariantStats struct {
+	URL    string `json:"url"`
+	Weight uint32 `json:"weight"`
+	Clicks uint64 `json:"clicks"`
+}
+
+// URLStats is an url together with its statistics
+type URLStats struct {
+	*URLInfo
+	Uniques       uint64         `json:"uniques"`
+	Clicks        *ClickSeries   `json:"clicks,omitempty"`
+	Geo           *GeoStats      `json:"geo,omitempty"`
+	VariantClicks []VariantStats `json:"variant_clicks,omitempty"`
+	// UTM are the utm parameters added to the targets
+	UTM *UTMParams `json:"utm,omitempty"`
+	// Protected tells if the url requires a password
+	Protected bool `json:"protected"`
+	// IdleExpireOn is the expiration of the url by its idle ttl
+	IdleExpireOn *time.Time `json:"idle_expire_on,omitempty"`
+	// Window is the usage of the current window of the rate limited urls
+	Window *WindowUsage `json:"window,omitempty"`
 }
 
 func (s *Statistics) String() string {
@@ -86,6 +324,78 @@ func (u URLInfo) ExpirationDate() time.Time {
 	return u.BountAt.Add(time.Duration(u.TTL) * time.Second)
 }
 
+// ETag returns the entity tag of the URLInfo settings,
+// the counters and the last visit are not part of the tag so redirects do not change it
+func (u URLInfo) ETag() string {
+	u.Counter, u.LastVisit = 0, time.Time{}
+	u.WindowStart, u.WindowCounter = time.Time{}, 0
+	data, _ := u.MarshalBinary()
+	h := sha1.Sum(data)
+	return hex.EncodeToString(h[:])
+}
+
+// matchETag tells if the value of an If-Match header (RFC 7232) matches
+// the etag, that is "*" or a list of etags, the weak ones never match
+// since If-Match uses the strong comparison
+func matchETag(ifMatch, etag string) bool {
+	for _, t := range strings.Split(ifMatch, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" {
+			return true
+		}
+		if strings.HasPrefix(t, "W/") {
+			continue
+		}
+		if strings.Trim(t, `"`) == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// State return the state of the URLInfo at the time now
+func (u URLInfo) State(now time.Time) string {
+	if !u.Active(now) {
+		return URLStateInactive
+	}
+	if u.Expired(now) {
+		return URLStateExpired
+	}
+	if l := u.RequestsLimit(); l > 0 && u.Counter >= l {
+		return URLStateExhausted
+	}
+	if u.WindowFull(now) {
+		return URLStateExhausted
+	}
+	return URLStateActive
+}
+
+// RequestsLimit returns the max number of requests of the url,
+// 1 for the one time urls, 0 if there is no limit
+func (u URLInfo) RequestsLimit() uint64 {
+	if u.OneTime {
+		return 1
+	}
+	return u.MaxRequests
+}
+
+// Match tells if an URLInfo matches the filter at the time now
+func (f *URLFilter) Match(u *URLInfo, now time.Time) bool {
+	if len(f.URL) > 0 && !strings.Contains(u.URL, f.URL) {
+		return false
+	}
+	if len(f.State) > 0 && u.State(now) != f.State {
+		return false
+	}
+	if !f.CreatedFrom.IsZero() && u.BountAt.Before(f.CreatedFrom) {
+		return false
+	}
+	if !f.CreatedTo.IsZero() && u.BountAt.After(f.CreatedTo) {
+		return false
+	}
+	return true
+}
+
 // String version of urlinfo
 func (u URLInfo) String() string {
 	//return fmt.Sprint("%#v", u)
@@ -102,6 +412,11 @@ func (u *ShortID) Bind(r *http.Request) error {
 	return nil
 }
 
+// Bind will run after the unmarshalling is complete
+func (u *URLPatch) Bind(r *http.Request) error {
+	return nil
+}
+
 //     ______   ______  ____   ____
 //   .' ___  |.' ____ \|_  _| |_  _|
 //  / .'   \_|| (___ \_| \ \   / /
@@ -110,9 +425,35 @@ func (u *ShortID) Bind(r *http.Request) error {
 //   `.____ .' \______.'    \_/
 //
 
-//MarshalRecord marshal a urlinfo to a string array (for csv)
+// recordPasswordIdx is the column of the password hash in the csv backups,
+// it is left empty by MarshalRecord and sealed by the store
+const recordPasswordIdx = 18
+
+// recordStateIdx is the column of the state of the url in the csv backups,
+// it is left empty by MarshalRecord and set by the store for the urls
+// in the archive or in the trash
+const recordStateIdx = 30
+
+// States of the urls in the csv backups, the live urls have no state
+const (
+	recordStateArchived = "archived"
+	recordStateTrashed  = "trashed"
+)
+
+// recordStates are the states of the urls in the
+// csv backups with the prefix of their keys
+var recordStates = []struct {
+	state  string
+	prefix byte
+}{
+	{"", keyURLPrefix},
+	{recordStateArchived, keyArchivePrefix},
+	{recordStateTrashed, keyTrashPrefix},
+}
+
+// MarshalRecord marshal a urlinfo to a string array (for csv)
 func (u *URLInfo) MarshalRecord() []string {
-	pieces := make([]string, 9)
+	pieces := make([]string, 31)
 	pieces[0] = u.ID
 	pieces[1] = u.URL
 	pieces[2] = fTime(u.BountAt)
@@ -122,13 +463,34 @@ func (u *URLInfo) MarshalRecord() []string {
 	pieces[6] = fUint64(u.TTL)
 	pieces[7] = fTime(u.ExpireOn)
 	pieces[8] = u.ExpiredURL
+	pieces[9] = fVariants(u.Variants)
+	pieces[10] = fRules(u.Rules)
+	pieces[11] = fLanguages(u.Languages)
+	pieces[12] = fCountries(u.Countries)
+	pieces[13] = fUint64(uint64(u.RedirectStatus))
+	pieces[14] = fUint64(uint64(u.ForwardQuery))
+	pieces[15] = fUint64(uint64(u.ForwardPath))
+	pieces[16] = u.UTM().Query()
+	pieces[17] = u.Campaign
+	pieces[19] = fBool(u.OneTime)
+	pieces[20] = fTime(u.ActiveFrom)
+	pieces[21] = u.InactiveURL
+	pieces[22] = fSchedule(u.Schedule)
+	pieces[23] = fUint64(u.IdleTTL)
+	pieces[24] = fTime(u.LastVisit)
+	pieces[25] = fUint64(u.WindowMaxRequests)
+	pieces[26] = fUint64(u.WindowSize)
+	pieces[27] = fTime(u.WindowStart)
+	pieces[28] = fUint64(u.WindowCounter)
+	pieces[29] = fTime(u.DeletedAt)
 	return pieces
 }
 
-//UnmarshalRecord unmarshal a string array into a urlinfo
+// UnmarshalRecord unmarshal a string array into a urlinfo
 func (u *URLInfo) UnmarshalRecord(pieces []string) (err error) {
 	pl := len(pieces)
-	if pl != 9 {
+	// backups made by older versions have less fields
+	if pl < 9 || pl > 31 {
 		return fmt.Errorf("Invalid backup record! record corrupted")
 	}
 	u.ID = pieces[0]
@@ -146,14 +508,83 @@ func (u *URLInfo) UnmarshalRecord(pieces []string) (err error) {
 	if u.TTL, err = pUint64(pieces, 6, pl); err != nil {
 		return
 	}
-	if u.BountAt, err = pTime(pieces, 7, pl); err != nil {
+	if u.ExpireOn, err = pTime(pieces, 7, pl); err != nil {
 		return
 	}
 	u.ExpiredURL = pieces[8]
+	if u.Variants, err = pVariants(pieces, 9, pl); err != nil {
+		return
+	}
+	if u.Rules, err = pRules(pieces, 10, pl); err != nil {
+		return
+	}
+	if u.Languages, err = pLanguages(pieces, 11, pl); err != nil {
+		return
+	}
+	if u.Countries, err = pCountries(pieces, 12, pl); err != nil {
+		return
+	}
+	status, err := pUint64(pieces, 13, pl)
+	if err != nil {
+		return
+	}
+	u.RedirectStatus = uint32(status)
+	forwardQuery, err := pUint64(pieces, 14, pl)
+	if err != nil {
+		return
+	}
+	forwardPath, err := pUint64(pieces, 15, pl)
+	if err != nil {
+		return
+	}
+	u.ForwardQuery, u.ForwardPath = uint32(forwardQuery), uint32(forwardPath)
+	utm, err := pUTM(pieces, 16, pl)
+	if err != nil {
+		return
+	}
+	u.SetUTM(utm)
+	if pl > 17 {
+		u.Campaign = pieces[17]
+	}
+	oneTime, err := pBool(pieces, 19, pl)
+	if err != nil {
+		return
+	}
+	u.OneTime = oneTime != nil && *oneTime
+	if u.ActiveFrom, err = pTime(pieces, 20, pl); err != nil {
+		return
+	}
+	if pl > 21 {
+		u.InactiveURL = pieces[21]
+	}
+	if u.Schedule, err = pSchedule(pieces, 22, pl); err != nil {
+		return
+	}
+	if u.IdleTTL, err = pUint64(pieces, 23, pl); err != nil {
+		return
+	}
+	if u.LastVisit, err = pTime(pieces, 24, pl); err != nil {
+		return
+	}
+	if u.WindowMaxRequests, err = pUint64(pieces, 25, pl); err != nil {
+		return
+	}
+	if u.WindowSize, err = pUint64(pieces, 26, pl); err != nil {
+		return
+	}
+	if u.WindowStart, err = pTime(pieces, 27, pl); err != nil {
+		return
+	}
+	if u.WindowCounter, err = pUint64(pieces, 28, pl); err != nil {
+		return
+	}
+	if u.DeletedAt, err = pTime(pieces, 29, pl); err != nil {
+		return
+	}
 	return
 }
 
-//UnmarshalRecord unmarshal a string array (csv record) to URLReq pointer
+// UnmarshalRecord unmarshal a string array (csv record) to URLReq pointer
 func (u *URLReq) UnmarshalRecord(pieces []string) (err error) {
 	u.URL = pieces[0]
 	p := len(pieces)
@@ -169,6 +600,75 @@ func (u *URLReq) UnmarshalRecord(pieces []string) (err error) {
 	if u.ExpireOn, err = pTime(pieces, 4, p); err != nil {
 		return
 	}
+	if u.Variants, err = pVariants(pieces, 5, p); err != nil {
+		return
+	}
+	if u.Rules, err = pRules(pieces, 6, p); err != nil {
+		return
+	}
+	languages, err := pLanguages(pieces, 7, p)
+	if err != nil {
+		return
+	}
+	for _, l := range languages {
+		if u.Languages == nil {
+			u.Languages = make(map[string]string)
+		}
+		u.Languages[l.Tag] = l.URL
+	}
+	countries, err := pCountries(pieces, 8, p)
+	if err != nil {
+		return
+	}
+	for _, c := range countries {
+		if u.Countries == nil {
+			u.Countries = make(map[string]string)
+		}
+		u.Countries[c.Code] = c.URL
+	}
+	status, err := pUint64(pieces, 9, p)
+	if err != nil {
+		return
+	}
+	u.RedirectStatus = int(status)
+	if u.ForwardQuery, err = pBool(pieces, 10, p); err != nil {
+		return
+	}
+	if u.ForwardPath, err = pBool(pieces, 11, p); err != nil {
+		return
+	}
+	if u.UTMParams, err = pUTM(pieces, 12, p); err != nil {
+		return
+	}
+	if p > 13 {
+		u.Campaign = pieces[13]
+	}
+	if p > 14 {
+		u.Password = pieces[14]
+	}
+	oneTime, err := pBool(pieces, 15, p)
+	if err != nil {
+		return
+	}
+	u.OneTime = oneTime != nil && *oneTime
+	if u.ActiveFrom, err = pTime(pieces, 16, p); err != nil {
+		return
+	}
+	if p > 17 {
+		u.InactiveURL = pieces[17]
+	}
+	if u.Schedule, err = pSchedule(pieces, 18, p); err != nil {
+		return
+	}
+	if u.IdleTTL, err = pUint64(pieces, 19, p); err != nil {
+		return
+	}
+	if u.WindowMaxRequests, err = pUint64(pieces, 20, p); err != nil {
+		return
+	}
+	if u.WindowSize, err = pUint64(pieces, 21, p); err != nil {
+		return
+	}
 	return
 }
 
@@ -189,6 +689,14 @@ func fUint64(v uint64) (str string) {
 	return
 }
 
+// fBool for csv printing
+func fBool(v bool) (str string) {
+	if v {
+		str = strconv.FormatBool(v)
+	}
+	return
+}
+
 // fTime for csv printing
 func fTime(v time.Time) (str string) {
 	if v.IsZero() {
@@ -198,6 +706,62 @@ func fTime(v time.Time) (str string) {
 	return
 }
 
+// fVariants for csv printing, the variants are
+// separated by spaces in the form weight:url
+func fVariants(variants []*Variant) (str string) {
+	pieces := make([]string, len(variants))
+	for i, v := range variants {
+		pieces[i] = fmt.Sprintf("%d:%s", v.Weight, v.URL)
+	}
+	return strings.Join(pieces, " ")
+}
+
+// fRules for csv printing, the rules are separated by spaces
+// in the form conditions:url, the conditions are query encoded
+func fRules(rules []*Rule) (str string) {
+	pieces := make([]string, len(rules))
+	for i, r := range rules {
+		c := url.Values{}
+		for k, v := range map[string]string{"device": r.Device, "os": r.OS, "bot": r.Bot} {
+			if len(v) > 0 {
+				c.Set(k, v)
+			}
+		}
+		pieces[i] = fmt.Sprintf("%s:%s", c.Encode(), r.URL)
+	}
+	return strings.Join(pieces, " ")
+}
+
+// fLanguages for csv printing, the languages are
+// separated by spaces in the form tag:url
+func fLanguages(languages []*Language) (str string) {
+	pieces := make([]string, len(languages))
+	for i, l := range languages {
+		pieces[i] = fmt.Sprintf("%s:%s", l.Tag, l.URL)
+	}
+	return strings.Join(pieces, " ")
+}
+
+// fCountries for csv printing, the countries are
+// separated by spaces in the form code:url
+func fCountries(countries []*Country) (str string) {
+	pieces := make([]string, len(countries))
+	for i, c := range countries {
+		pieces[i] = fmt.Sprintf("%s:%s", c.Code, c.URL)
+	}
+	return strings.Join(pieces, " ")
+}
+
+// fSchedule for csv printing, the changes are
+// separated by spaces in the form time=url
+func fSchedule(schedule []*Change) (str string) {
+	pieces := make([]string, len(schedule))
+	for i, c := range schedule {
+		pieces[i] = fmt.Sprintf("%s=%s", c.At.Format(time.RFC3339), c.URL)
+	}
+	return strings.Join(pieces, " ")
+}
+
 // pInt64 parse an int64 from a string
 func pInt64(src []string, idx, srcLen int) (v int64, err error) {
 	if idx < srcLen && len(src[idx]) > 0 {
@@ -214,6 +778,26 @@ func pUint64(src []string, idx, srcLen int) (v uint64, err error) {
 	return
 }
 
+// pBool parse an optional bool from a string
+func pBool(src []string, idx, srcLen int) (v *bool, err error) {
+	if idx < srcLen && len(src[idx]) > 0 {
+		b, err := strconv.ParseBool(src[idx])
+		if err != nil {
+			return nil, err
+		}
+		v = &b
+	}
+	return
+}
+
+// pUTM parse the utm parameters from a query string
+func pUTM(src []string, idx, srcLen int) (p UTMParams, err error) {
+	if idx < srcLen && len(src[idx]) > 0 {
+		return parseUTMQuery(src[idx])
+	}
+	return
+}
+
 // pTime parse a time.Time from a string
 func pTime(src []string, idx, srcLen int) (v time.Time, err error) {
 	if idx < srcLen && len(src[idx]) > 0 {
@@ -222,6 +806,95 @@ func pTime(src []string, idx, srcLen int) (v time.Time, err error) {
 	return
 }
 
+// pVariants parse the variants printed with fVariants from a string
+func pVariants(src []string, idx, srcLen int) (v []*Variant, err error) {
+	if idx >= srcLen {
+		return
+	}
+	for _, f := range strings.Fields(src[idx]) {
+		pieces := strings.SplitN(f, ":", 2)
+		if len(pieces) != 2 {
+			return nil, fmt.Errorf("Invalid variant %v", f)
+		}
+		w, err := strconv.ParseUint(pieces[0], 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		v = append(v, &Variant{URL: pieces[1], Weight: uint32(w)})
+	}
+	return
+}
+
+// pRules parse the rules printed with fRules from a string
+func pRules(src []string, idx, srcLen int) (r []*Rule, err error) {
+	if idx >= srcLen {
+		return
+	}
+	for _, f := range strings.Fields(src[idx]) {
+		pieces := strings.SplitN(f, ":", 2)
+		if len(pieces) != 2 {
+			return nil, fmt.Errorf("Invalid rule %v", f)
+		}
+		c, err := url.ParseQuery(pieces[0])
+		if err != nil {
+			return nil, err
+		}
+		r = append(r, &Rule{Device: c.Get("device"), OS: c.Get("os"), Bot: c.Get("bot"), URL: pieces[1]})
+	}
+	return
+}
+
+// pLanguages parse the languages printed with fLanguages from a string
+func pLanguages(src []string, idx, srcLen int) (l []*Language, err error) {
+	if idx >= srcLen {
+		return
+	}
+	for _, f := range strings.Fields(src[idx]) {
+		pieces := strings.SplitN(f, ":", 2)
+		if len(pieces) != 2 {
+			return nil, fmt.Errorf("Invalid language %v", f)
+		}
+		l = append(l, &Language{Tag: pieces[0], URL: pieces[1]})
+	}
+	return
+}
+
+// pCountries parse the countries printed with fCountries from a string
+func pCountries(src []string, idx, srcLen int) (c []*Country, err error) {
+	if idx >= srcLen {
+		return
+	}
+	for _, f := range strings.Fields(src[idx]) {
+		pieces := strings.SplitN(f, ":", 2)
+		if len(pieces) != 2 {
+			return nil, fmt.Errorf("Invalid country %v", f)
+		}
+		c = append(c, &Country{Code: pieces[0], URL: pieces[1]})
+	}
+	return
+}
+
+// pSchedule parse the changes printed with fSchedule from a string,
+// the changes are sorted by time
+func pSchedule(src []string, idx, srcLen int) (s []*Change, err error) {
+	if idx >= srcLen {
+		return
+	}
+	for _, f := range strings.Fields(src[idx]) {
+		pieces := strings.SplitN(f, "=", 2)
+		if len(pieces) != 2 {
+			return nil, fmt.Errorf("Invalid change %v", f)
+		}
+		at, err := time.Parse(time.RFC3339, pieces[0])
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, &Change{At: at, URL: pieces[1]})
+	}
+	sort.SliceStable(s, func(i, j int) bool { return s[i].At.Before(s[j].At) })
+	return
+}
+
 func itoa(i uint64) (b []byte) {
 	b = make([]byte, 8)
 	binary.BigEndian.PutUint64(b, i)
@@ -242,6 +915,8 @@ func opcodeToString(opcode int) (label string) {
 		label = "DEL"
 	case opcodeExpired:
 		label = "EXP"
+	case opcodeUpdate:
+		label = "UPD"
 	}
 	return
 }
@@ -260,6 +935,66 @@ var ErrURLExpired = fmt.Errorf("url expired")
 // ErrURLExhausted when url is expired
 var ErrURLExhausted = fmt.Errorf("url exhausted")
 
+// ErrURLInactive when url is not active yet
+var ErrURLInactive = fmt.Errorf("url not active yet")
+
+// ErrURLRateLimited when the requests of the current window are exhausted
+var ErrURLRateLimited = fmt.Errorf("url rate limited, try again later")
+
+// ErrInvalidWindow when a window has max requests but no size
+var ErrInvalidWindow = fmt.Errorf("invalid window, the window size is required")
+
+// ErrURLExists when the id of a restored url is already in use
+var ErrURLExists = fmt.Errorf("url id already in use")
+
+// ErrURLTrashed when the id of an url is reserved by an url in the trash
+var ErrURLTrashed = fmt.Errorf("url id in the trash, restore it or wait for the purge")
+
+// ErrURLArchived when the id of an url is reserved by an archived url
+var ErrURLArchived = fmt.Errorf("url id reserved by an archived url")
+
+// ErrInvalidChange when a scheduled change has no time or no url
+var ErrInvalidChange = fmt.Errorf("invalid change, time and url are required")
+
+// ErrInvalidVariant when a variant has no url or no weight
+var ErrInvalidVariant = fmt.Errorf("invalid variant, url and weight are required")
+
+// ErrInvalidRule when a rule has no url, no conditions or an unknown device
+var ErrInvalidRule = fmt.Errorf("invalid rule, url and a known device, os or bot are required")
+
+// ErrInvalidLanguage when a language has an invalid tag or no url
+var ErrInvalidLanguage = fmt.Errorf("invalid language, a language tag and url are required")
+
+// ErrInvalidCountry when a country has an invalid code or no url
+var ErrInvalidCountry = fmt.Errorf("invalid country, an ISO country code and url are required")
+
+// ErrInvalidRedirectStatus when the redirect status is not a redirect
+var ErrInvalidRedirectStatus = fmt.Errorf("invalid redirect status, allowed are %v", RedirectStatuses)
+
+// ErrETagMismatch when the url has been modified since the etag was read
+var ErrETagMismatch = fmt.Errorf("url has been modified")
+
+// ErrInvalidUTM when the utm parameters are not valid
+var ErrInvalidUTM = fmt.Errorf("invalid utm parameters, allowed are %v", utmParams)
+
+// ErrInvalidCampaign when the campaign is not in the configuration
+var ErrInvalidCampaign = fmt.Errorf("invalid campaign, the campaign is not configured")
+
+// ErrPasswordRequired when the url is protected and no password is provided
+var ErrPasswordRequired = fmt.Errorf("password required")
+
+// ErrWrongPassword when the password of a protected url is wrong
+var ErrWrongPassword = fmt.Errorf("wrong password")
+
+// ErrTooManyAttempts when a client exceeded the password attempts
+var ErrTooManyAttempts = fmt.Errorf("too many password attempts, try again later")
+
+// ErrInvalidSealedSecret when a secret in a backup cannot be decrypted
+var ErrInvalidSealedSecret = fmt.Errorf("invalid sealed secret, the backup was made with a different api key")
+
+// ErrInvalidBatchCount when the number of urls of a batch is out of range
+var ErrInvalidBatchCount = fmt.Errorf("invalid count, a batch has from 1 to %d urls", BatchMaxCount)
+
 // ErrInvalidBackupRecord when a csv record from backup is different from expected
 var ErrInvalidBackupRecord = fmt.Errorf("Invalid backup record")
 
@@ -275,6 +1010,14 @@ func keyURL(id string) (k []byte, err error) {
 	return key(keyURLPrefix, id)
 }
 
+func keyArchive(id string) (k []byte, err error) {
+	return key(keyArchivePrefix, id)
+}
+
+func keyTrash(id string) (k []byte, err error) {
+	return key(keyTrashPrefix, id)
+}
+
 func keySys(id string) (k []byte) {
 	k, _ = key(keySysPrefix, id)
 	return
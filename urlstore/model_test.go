@@ -224,3 +224,260 @@ func TestURLInfo_ExpirationDate(t *testing.T) {
 		})
 	}
 }
+
+func TestURLInfo_Variants(t *testing.T) {
+	u := &URLInfo{
+		ID:  "ab",
+		URL: "https://ex.com",
+		Variants: []*Variant{
+			{URL: "https://ex.com/a", Weight: 70},
+			{URL: "https://ex.com/b?x=1:2", Weight: 1 << 22},
+		},
+	}
+	data, err := u.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := &URLInfo{}
+	if err = got.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Variants, u.Variants) {
+		t.Errorf("UnmarshalBinary() = %v, want %v", got.Variants, u.Variants)
+	}
+
+	record := u.MarshalRecord()
+	if want := "70:https://ex.com/a 4194304:https://ex.com/b?x=1:2"; record[9] != want {
+		t.Errorf("MarshalRecord() = %v, want %v", record[9], want)
+	}
+	got = &URLInfo{}
+	if err = got.UnmarshalRecord(record); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Variants, u.Variants) {
+		t.Errorf("UnmarshalRecord() = %v, want %v", got.Variants, u.Variants)
+	}
+	// records without variants
+	got = &URLInfo{}
+	if err = got.UnmarshalRecord(record[:9]); err != nil || got.Variants != nil {
+		t.Errorf("UnmarshalRecord() = %v, %v", got.Variants, err)
+	}
+}
+
+func Test_pVariants(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		want    []*Variant
+		wantErr bool
+	}{
+		{"empty", "", nil, false},
+		{"one", "1:https://ex.com", []*Variant{{URL: "https://ex.com", Weight: 1}}, false},
+		{"spaces", " 2:a  3:b ", []*Variant{{URL: "a", Weight: 2}, {URL: "b", Weight: 3}}, false},
+		{"no weight", "https://ex.com", nil, true},
+		{"invalid weight", "x:https://ex.com", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pVariants([]string{tt.src}, 0, 1)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("pVariants() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pVariants() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestURLInfo_Rules(t *testing.T) {
+	u := &URLInfo{
+		ID:  "ab",
+		URL: "https://ex.com",
+		Rules: []*Rule{
+			{OS: "Chrome OS", Device: "desktop", URL: "https://ex.com/a?b=1:2"},
+			{Bot: "bot", URL: "https://ex.com/bot"},
+		},
+	}
+	data, err := u.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := &URLInfo{}
+	if err = got.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Rules, u.Rules) {
+		t.Errorf("UnmarshalBinary() = %v, want %v", got.Rules, u.Rules)
+	}
+
+	record := u.MarshalRecord()
+	if want := "device=desktop&os=Chrome+OS:https://ex.com/a?b=1:2 bot=bot:https://ex.com/bot"; record[10] != want {
+		t.Errorf("MarshalRecord() = %v, want %v", record[10], want)
+	}
+	got = &URLInfo{}
+	if err = got.UnmarshalRecord(record); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Rules, u.Rules) {
+		t.Errorf("UnmarshalRecord() = %v, want %v", got.Rules, u.Rules)
+	}
+}
+
+func TestURLInfo_Languages(t *testing.T) {
+	u := &URLInfo{
+		ID:  "ab",
+		URL: "https://ex.com",
+		Languages: []*Language{
+			{Tag: "de", URL: "https://ex.com/de"},
+			{Tag: "pt-BR", URL: "https://ex.com/pt?a=1:2"},
+		},
+	}
+	data, err := u.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := &URLInfo{}
+	if err = got.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Languages, u.Languages) {
+		t.Errorf("UnmarshalBinary() = %v, want %v", got.Languages, u.Languages)
+	}
+
+	record := u.MarshalRecord()
+	if want := "de:https://ex.com/de pt-BR:https://ex.com/pt?a=1:2"; record[11] != want {
+		t.Errorf("MarshalRecord() = %v, want %v", record[11], want)
+	}
+	got = &URLInfo{}
+	if err = got.UnmarshalRecord(record); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Languages, u.Languages) {
+		t.Errorf("UnmarshalRecord() = %v, want %v", got.Languages, u.Languages)
+	}
+}
+
+func TestURLInfo_Passthrough(t *testing.T) {
+	u := &URLInfo{ID: "ab", URL: "https://ex.com", ForwardQuery: PassthroughOn, ForwardPath: PassthroughOff}
+	record := u.MarshalRecord()
+	got := &URLInfo{}
+	if err := got.UnmarshalRecord(record); err != nil {
+		t.Fatal(err)
+	}
+	if got.ForwardQuery != u.ForwardQuery || got.ForwardPath != u.ForwardPath {
+		t.Errorf("UnmarshalRecord() = %v %v, want %v %v", got.ForwardQuery, got.ForwardPath, u.ForwardQuery, u.ForwardPath)
+	}
+
+	req := &URLReq{}
+	if err := req.UnmarshalRecord([]string{"https://ex.com", "", "", "", "", "", "", "", "", "", "true", ""}); err != nil {
+		t.Fatal(err)
+	}
+	if req.ForwardQuery == nil || !*req.ForwardQuery || req.ForwardPath != nil {
+		t.Errorf("UnmarshalRecord() = %v %v, want true nil", req.ForwardQuery, req.ForwardPath)
+	}
+	if err := req.UnmarshalRecord([]string{"https://ex.com", "", "", "", "", "", "", "", "", "", "yes"}); err == nil {
+		t.Errorf("UnmarshalRecord() expected error")
+	}
+}
+
+func TestURLInfo_UTM(t *testing.T) {
+	u := &URLInfo{ID: "ab", URL: "https://ex.com", UTMSource: "news", UTMCampaign: "a b", Campaign: "weekly"}
+	record := u.MarshalRecord()
+	if want := "utm_source=news&utm_campaign=a+b"; record[16] != want {
+		t.Errorf("MarshalRecord() = %v, want %v", record[16], want)
+	}
+	got := &URLInfo{}
+	if err := got.UnmarshalRecord(record); err != nil {
+		t.Fatal(err)
+	}
+	if got.UTM() != u.UTM() || got.Campaign != u.Campaign {
+		t.Errorf("UnmarshalRecord() = %v %v, want %v %v", got.UTM(), got.Campaign, u.UTM(), u.Campaign)
+	}
+
+	req := &URLReq{}
+	if err := req.UnmarshalRecord([]string{"https://ex.com", "", "", "", "", "", "", "", "", "", "", "", "utm_medium=email", "weekly"}); err != nil {
+		t.Fatal(err)
+	}
+	if req.Medium != "email" || req.Campaign != "weekly" {
+		t.Errorf("UnmarshalRecord() = %v %v, want email weekly", req.UTMParams, req.Campaign)
+	}
+}
+
+func Test_matchETag(t *testing.T) {
+	tests := []struct {
+		ifMatch string
+		want    bool
+	}{
+		{`"abc"`, true},
+		{`abc`, true},
+		{`*`, true},
+		{`"xyz"`, false},
+		{`"xyz", "abc"`, true},
+		{`"xyz","abc"`, true},
+		{`W/"abc"`, false},
+		{`W/"abc", "xyz"`, false},
+		{`"ab"`, false},
+	}
+	for _, tt := range tests {
+		if got := matchETag(tt.ifMatch, "abc"); got != tt.want {
+			t.Errorf("matchETag(%v) = %v, want %v", tt.ifMatch, got, tt.want)
+		}
+	}
+}
+
+func TestURLInfo_Schedule(t *testing.T) {
+	t0 := time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+	u := &URLInfo{
+		ID:          "ab",
+		URL:         "https://ex.com",
+		BountAt:     t0.Add(-24 * time.Hour),
+		ExpireOn:    t0.Add(24 * time.Hour),
+		ActiveFrom:  t0,
+		InactiveURL: "https://ex.com/soon",
+		IdleTTL:     60,
+		LastVisit:   t0.Add(time.Minute),
+		Schedule: []*Change{
+			{At: t0, URL: "https://ex.com/live?a=1"},
+			{At: t0.Add(time.Hour), URL: "https://ex.com/recap"},
+		},
+	}
+	data, err := u.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := &URLInfo{}
+	if err = got.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, u) {
+		t.Errorf("UnmarshalBinary() = %v, want %v", got, u)
+	}
+
+	record := u.MarshalRecord()
+	if want := "2020-05-01T10:00:00Z=https://ex.com/live?a=1 2020-05-01T11:00:00Z=https://ex.com/recap"; record[22] != want {
+		t.Errorf("MarshalRecord() = %v, want %v", record[22], want)
+	}
+	got = &URLInfo{}
+	if err = got.UnmarshalRecord(record); err != nil {
+		t.Fatal(err)
+	}
+	if !got.BountAt.Equal(u.BountAt) || !got.ExpireOn.Equal(u.ExpireOn) || !got.ActiveFrom.Equal(u.ActiveFrom) {
+		t.Errorf("UnmarshalRecord() dates = %v %v %v", got.BountAt, got.ExpireOn, got.ActiveFrom)
+	}
+	if got.IdleTTL != u.IdleTTL || !got.LastVisit.Equal(u.LastVisit) {
+		t.Errorf("UnmarshalRecord() idle = %v %v", got.IdleTTL, got.LastVisit)
+	}
+	if got.InactiveURL != u.InactiveURL || len(got.Schedule) != 2 || got.Schedule[1].URL != "https://ex.com/recap" {
+		t.Errorf("UnmarshalRecord() = %v, want %v", got, u)
+	}
+
+	req := &URLReq{}
+	if err := req.UnmarshalRecord([]string{"https://ex.com", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "",
+		"2020-05-01T10:00:00Z", "https://ex.com/soon", "2020-05-01T11:00:00Z=https://ex.com/recap"}); err != nil {
+		t.Fatal(err)
+	}
+	if !req.ActiveFrom.Equal(t0) || req.InactiveURL != "https://ex.com/soon" || len(req.Schedule) != 1 {
+		t.Errorf("UnmarshalRecord() = %v", req)
+	}
+}
@@ -0,0 +1,138 @@
+package urlstore
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOneTime(t *testing.T) {
+	t.Parallel()
+	cfg := buildConifgTest()
+	cfg.ShortID.ExhaustedRedirectURL = "https://ilij.li/used"
+	s := openBackendStore(t, BackendMemory, cfg)
+	defer s.Close()
+
+	id, err := s.UpsertURLSimple(&URLReq{URL: "https://ilij.li/a", OneTime: true})
+	require.NoError(t, err)
+	// concurrent requests, only one is redirected
+	var wg sync.WaitGroup
+	var m sync.Mutex
+	ok := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r, err := s.Redirect(id, nil)
+			if err == nil {
+				m.Lock()
+				ok++
+				m.Unlock()
+				require.Equal(t, "https://ilij.li/a", r.URL)
+				return
+			}
+			require.Equal(t, ErrURLExhausted, err)
+			require.Equal(t, s.Config.ShortID.ExhaustedRedirectURL, r.URL)
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, 1, ok)
+
+	// the counter is already in the storage
+	err = s.db.View(func(txn Txn) error {
+		key, _ := keyURL(id)
+		u := &URLInfo{}
+		if err := dbGetBin(txn, key, u); err != nil {
+			return err
+		}
+		require.Equal(t, uint64(50), u.Counter)
+		require.Equal(t, URLStateExhausted, u.State(time.Now()))
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestMaxRequestsConcurrent(t *testing.T) {
+	t.Parallel()
+	s := openBackendStore(t, BackendMemory, buildConifgTest())
+	defer s.Close()
+
+	id, err := s.UpsertURLSimple(&URLReq{URL: "https://ilij.li/a", MaxRequests: 5})
+	require.NoError(t, err)
+	var wg sync.WaitGroup
+	var m sync.Mutex
+	ok := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.Redirect(id, nil); err == nil {
+				m.Lock()
+				ok++
+				m.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, 5, ok)
+}
+
+func TestUpsertURLs(t *testing.T) {
+	t.Parallel()
+	s := openBackendStore(t, BackendMemory, buildConifgTest())
+	defer s.Close()
+
+	urls := s.GetStats().Urls
+	ids, err := s.UpsertURLs(&URLReq{URL: "https://ilij.li/a", OneTime: true}, 20, time.Now())
+	require.NoError(t, err)
+	require.Len(t, ids, 20)
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		require.False(t, seen[id])
+		seen[id] = true
+		_, err = s.Redirect(id, nil)
+		require.NoError(t, err)
+		_, err = s.Redirect(id, nil)
+		require.Equal(t, ErrURLExhausted, err)
+	}
+	require.Equal(t, urls+20, s.GetStats().Urls)
+
+	_, err = s.UpsertURLs(&URLReq{URL: "https://ilij.li/a"}, 0, time.Now())
+	require.Equal(t, ErrInvalidBatchCount, err)
+	_, err = s.UpsertURLs(&URLReq{URL: "https://ilij.li/a"}, BatchMaxCount+1, time.Now())
+	require.Equal(t, ErrInvalidBatchCount, err)
+	_, err = s.UpsertURLs(&URLReq{ID: "abc", URL: "https://ilij.li/a"}, 2, time.Now())
+	require.Error(t, err)
+}
+
+func TestURLLocks(t *testing.T) {
+	t.Parallel()
+	s := openBackendStore(t, BackendMemory, buildConifgTest())
+	defer s.Close()
+	a, err := s.UpsertURLSimple(&URLReq{URL: "https://ilij.li/a"})
+	require.NoError(t, err)
+	require.Equal(t, s.urlM.of(a), s.urlM.of(a))
+	// an url with a different lock
+	var b string
+	for b == "" || s.urlM.of(b) == s.urlM.of(a) {
+		b, err = s.UpsertURLSimple(&URLReq{URL: "https://ilij.li/b"})
+		require.NoError(t, err)
+	}
+	// a slow update of an url does not stop the redirects of the others
+	um := s.urlM.of(a)
+	um.Lock()
+	done := make(chan error)
+	go func() {
+		_, err := s.Redirect(b, nil)
+		done <- err
+	}()
+	select {
+	case err = <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("redirect blocked by the lock of another url")
+	}
+	um.Unlock()
+}
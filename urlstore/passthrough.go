@@ -0,0 +1,110 @@
+package urlstore
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Passthrough options of an url, with PassthroughDefault
+// the option in the configuration is used
+const (
+	PassthroughDefault = 0
+	PassthroughOn      = 1
+	PassthroughOff     = 2
+)
+
+// passthroughOption converts an optional flag to a passthrough option
+func passthroughOption(enabled *bool) uint32 {
+	switch {
+	case enabled == nil:
+		return PassthroughDefault
+	case *enabled:
+		return PassthroughOn
+	}
+	return PassthroughOff
+}
+
+// passthroughEnabled tells if a passthrough option is enabled,
+// the default is the one of the configuration
+func passthroughEnabled(option uint32, global bool) bool {
+	switch option {
+	case PassthroughOn:
+		return true
+	case PassthroughOff:
+		return false
+	}
+	return global
+}
+
+// passthrough forwards the trailing path and the query string of
+// the visit to the target, if enabled for the url or globally
+func (s *Store) passthrough(u *URLInfo, target string, v *Visit) string {
+	path := len(v.Path) > 0 && passthroughEnabled(u.ForwardPath, s.Config.ShortID.ForwardPath)
+	query := len(v.Query) > 0 && passthroughEnabled(u.ForwardQuery, s.Config.ShortID.ForwardQuery)
+	if !path && !query {
+		return target
+	}
+	t, err := url.Parse(target)
+	if err != nil {
+		return target
+	}
+	if path {
+		appendPath(t, v.Path)
+	}
+	if query {
+		t.RawQuery = mergeQuery(t.RawQuery, v.Query)
+	}
+	return t.String()
+}
+
+// appendPath appends an escaped path to the path of the url,
+// the empty, the current and the parent segments are dropped
+func appendPath(u *url.URL, escapedPath string) {
+	segments := []string{strings.TrimSuffix(u.EscapedPath(), "/")}
+	for _, s := range strings.Split(escapedPath, "/") {
+		if p, err := url.PathUnescape(s); err != nil || len(p) == 0 || p == "." || p == ".." {
+			continue
+		}
+		segments = append(segments, s)
+	}
+	if len(segments) == 1 {
+		return
+	}
+	ep := strings.Join(segments, "/")
+	p, err := url.PathUnescape(ep)
+	if err != nil {
+		return
+	}
+	u.Path, u.RawPath = p, ep
+}
+
+// mergeQuery adds the parameters of a query string to the raw query of
+// a target, the parameters already in the target are not overwritten
+// and the order of the parameters is preserved
+func mergeQuery(rawQuery, query string) string {
+	target, _ := url.ParseQuery(rawQuery)
+	pieces := []string{}
+	if len(rawQuery) > 0 {
+		pieces = append(pieces, rawQuery)
+	}
+	for _, pair := range strings.Split(query, "&") {
+		kv := strings.SplitN(pair, "=", 2)
+		k, err := url.QueryUnescape(kv[0])
+		if err != nil || len(k) == 0 {
+			continue
+		}
+		if _, exists := target[k]; exists {
+			continue
+		}
+		p := url.QueryEscape(k)
+		if len(kv) == 2 {
+			v, err := url.QueryUnescape(kv[1])
+			if err != nil {
+				continue
+			}
+			p += "=" + url.QueryEscape(v)
+		}
+		pieces = append(pieces, p)
+	}
+	return strings.Join(pieces, "&")
+}
@@ -0,0 +1,106 @@
+package urlstore
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_mergeQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		rawQuery string
+		query    string
+		want     string
+	}{
+		{"empty", "", "", ""},
+		{"no target query", "", "a=1&b=2", "a=1&b=2"},
+		{"no incoming query", "a=1", "", "a=1"},
+		{"merge", "a=1", "b=2&c=3", "a=1&b=2&c=3"},
+		{"target wins", "a=1&b=2", "b=3&c=4", "a=1&b=2&c=4"},
+		{"repeated", "", "a=1&a=2", "a=1&a=2"},
+		{"no value", "", "flag&a=", "flag&a="},
+		{"encoding", "q=a+b", "x=%C3%A8+%26&y=a/b", "q=a+b&x=%C3%A8+%26&y=a%2Fb"},
+		{"invalid", "", "a=%zz&%zz=1&=1&b=2", "b=2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, mergeQuery(tt.rawQuery, tt.query))
+		})
+	}
+}
+
+func Test_appendPath(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		path   string
+		want   string
+	}{
+		{"empty", "https://ilij.li/a", "", "https://ilij.li/a"},
+		{"append", "https://ilij.li/a", "b/c", "https://ilij.li/a/b/c"},
+		{"no target path", "https://ilij.li", "b", "https://ilij.li/b"},
+		{"trailing slash", "https://ilij.li/a/", "b/", "https://ilij.li/a/b"},
+		{"dot segments", "https://ilij.li/a", "../../b/./c", "https://ilij.li/a/b/c"},
+		{"encoded dot segments", "https://ilij.li/a", "%2E%2E/b", "https://ilij.li/a/b"},
+		{"escaped", "https://ilij.li/a", "b%20c/d%2Fe", "https://ilij.li/a/b%20c/d%2Fe"},
+		{"invalid", "https://ilij.li/a", "%zz/b", "https://ilij.li/a/b"},
+		{"query and fragment", "https://ilij.li/a?x=1#top", "b", "https://ilij.li/a/b?x=1#top"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.target)
+			require.NoError(t, err)
+			appendPath(u, tt.path)
+			require.Equal(t, tt.want, u.String())
+		})
+	}
+}
+
+func TestPassthrough(t *testing.T) {
+	t.Parallel()
+	cfg := buildConifgTest()
+	cfg.ShortID.ForwardQuery = true
+	s := openBackendStore(t, BackendMemory, cfg)
+	defer s.Close()
+
+	on, off := true, false
+	tests := []struct {
+		name  string
+		req   URLReq
+		visit *Visit
+		want  string
+	}{
+		{"global", URLReq{URL: "https://ilij.li/a?x=1"}, &Visit{Path: "b", Query: "x=2&y=3"}, "https://ilij.li/a?x=1&y=3"},
+		{"query off", URLReq{URL: "https://ilij.li/a", ForwardQuery: &off}, &Visit{Query: "y=3"}, "https://ilij.li/a"},
+		{"path on", URLReq{URL: "https://ilij.li/a", ForwardPath: &on}, &Visit{Path: "b/c", Query: "y=3"}, "https://ilij.li/a/b/c?y=3"},
+		{"both", URLReq{URL: "https://ilij.li/a", ForwardQuery: &on, ForwardPath: &on}, &Visit{Path: "b"}, "https://ilij.li/a/b"},
+		{"no visit", URLReq{URL: "https://ilij.li/a", ForwardPath: &on}, nil, "https://ilij.li/a"},
+		{"variants", URLReq{URL: "https://ilij.li/a", ForwardPath: &on, Variants: []*Variant{{URL: "https://ilij.li/v", Weight: 1}}}, &Visit{Path: "b", Query: "y=3"}, "https://ilij.li/v/b?y=3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := s.UpsertURLSimple(&tt.req)
+			require.NoError(t, err)
+			r, err := s.Redirect(id, tt.visit)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, r.URL)
+		})
+	}
+
+	// the expired urls are not forwarded
+	id, err := s.UpsertURLSimple(&URLReq{URL: "https://ilij.li/a", MaxRequests: 1, ForwardPath: &on})
+	require.NoError(t, err)
+	s.Redirect(id, nil)
+	s.Redirect(id, nil)
+	r, err := s.Redirect(id, &Visit{Path: "b", Query: "y=3"})
+	require.Equal(t, ErrURLExhausted, err)
+	require.Equal(t, cfg.ShortID.ExhaustedRedirectURL, r.URL)
+
+	// patch
+	u, err := s.PatchURL(id, &URLPatch{ForwardQuery: &off, ForwardPath: &off}, "")
+	require.NoError(t, err)
+	require.Equal(t, uint32(PassthroughOff), u.ForwardQuery)
+	require.Equal(t, uint32(PassthroughOff), u.ForwardPath)
+}
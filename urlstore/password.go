@@ -0,0 +1,148 @@
+package urlstore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// passwordAttempts are the failed attempts of a client or of all the clients on an url
+type passwordAttempts struct {
+	count int
+	since time.Time
+}
+
+// hashPassword returns the bcrypt hash of a password,
+// an empty password returns an empty hash
+func hashPassword(password string) (hash string, err error) {
+	if len(password) == 0 {
+		return
+	}
+	h, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(h), err
+}
+
+// Protected tells if the url requires a password
+func (u *URLInfo) Protected() bool {
+	return len(u.PasswordHash) > 0
+}
+
+// checkPassword verifies the password of the visit for a protected url,
+// the failed attempts are limited by short_id.password_max_attempts per client
+// and by short_id.password_max_url_attempts per url, since the client ip
+// can be spoofed, every short_id.password_lockout seconds
+func (s *Store) checkPassword(u *URLInfo, v *Visit) error {
+	if v == nil || len(v.Password) == 0 {
+		return ErrPasswordRequired
+	}
+	clientKey, urlKey := u.ID+"\x00"+v.IP, u.ID
+	now := time.Now()
+	window := time.Duration(s.Config.ShortID.PasswordLockout) * time.Second
+	// reserve the attempt before the comparison,
+	// so the parallel attempts count against the limits
+	s.attemptsM.Lock()
+	ca := s.attemptsOf(clientKey, now, window)
+	ua := s.attemptsOf(urlKey, now, window)
+	if ca.count >= s.Config.ShortID.PasswordMaxAttempts || ua.count >= s.Config.ShortID.PasswordMaxURLAttempts {
+		s.attemptsM.Unlock()
+		return ErrTooManyAttempts
+	}
+	ca.count++
+	ua.count++
+	s.attemptsM.Unlock()
+
+	// compare outside of the lock, bcrypt is slow on purpose
+	if bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(v.Password)) != nil {
+		return ErrWrongPassword
+	}
+	// release the reservation
+	s.attemptsM.Lock()
+	delete(s.attempts, clientKey)
+	if ua, found := s.attempts[urlKey]; found && ua.count > 0 {
+		ua.count--
+	}
+	s.attemptsM.Unlock()
+	return nil
+}
+
+// attemptsOf returns the attempts for a key in the current lockout window,
+// the attemptsM lock must be held
+func (s *Store) attemptsOf(key string, now time.Time, window time.Duration) *passwordAttempts {
+	a, found := s.attempts[key]
+	if !found || now.Sub(a.since) >= window {
+		a = &passwordAttempts{since: now}
+		s.attempts[key] = a
+	}
+	return a
+}
+
+// pruneAttempts removes the failed attempts older than the lockout
+func (s *Store) pruneAttempts(now time.Time) {
+	window := time.Duration(s.Config.ShortID.PasswordLockout) * time.Second
+	s.attemptsM.Lock()
+	defer s.attemptsM.Unlock()
+	for k, a := range s.attempts {
+		if now.Sub(a.since) >= window {
+			delete(s.attempts, k)
+		}
+	}
+}
+
+// backupKey is the key used to seal the secrets in the csv backups,
+// derived from the api key of the server
+func (s *Store) backupKey() []byte {
+	k := sha256.Sum256([]byte("distill backup " + s.Config.Server.APIKey))
+	return k[:]
+}
+
+// sealSecret encrypts a secret for a csv backup (AES-GCM)
+func (s *Store) sealSecret(secret string) (sealed string, err error) {
+	if len(secret) == 0 {
+		return
+	}
+	block, err := aes.NewCipher(s.backupKey())
+	if err != nil {
+		return
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return
+	}
+	return base64.RawURLEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(secret), nil)), nil
+}
+
+// openSecret decrypts a secret sealed with sealSecret
+func (s *Store) openSecret(sealed string) (secret string, err error) {
+	if len(sealed) == 0 {
+		return
+	}
+	data, err := base64.RawURLEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", ErrInvalidSealedSecret
+	}
+	block, err := aes.NewCipher(s.backupKey())
+	if err != nil {
+		return
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", ErrInvalidSealedSecret
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", ErrInvalidSealedSecret
+	}
+	return string(plain), nil
+}
@@ -0,0 +1,160 @@
+package urlstore
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPassword(t *testing.T) {
+	t.Parallel()
+	cfg := buildConifgTest()
+	cfg.ShortID.PasswordMaxAttempts = 2
+	s := openBackendStore(t, BackendMemory, cfg)
+	defer s.Close()
+
+	id, err := s.UpsertURLSimple(&URLReq{URL: "https://ilij.li/a", Password: "s3cret"})
+	require.NoError(t, err)
+	u, err := s.Peek(id)
+	require.NoError(t, err)
+	require.True(t, u.Protected())
+	require.NotContains(t, u.PasswordHash, "s3cret")
+
+	// the hash is never serialized to json
+	data, err := json.Marshal(URLStats{URLInfo: u, Protected: u.Protected()})
+	require.NoError(t, err)
+	require.NotContains(t, string(data), u.PasswordHash)
+
+	// the visits without the right password are not counted
+	_, err = s.Redirect(id, nil)
+	require.Equal(t, ErrPasswordRequired, err)
+	_, err = s.Redirect(id, &Visit{IP: "1.1.1.1"})
+	require.Equal(t, ErrPasswordRequired, err)
+	_, err = s.Redirect(id, &Visit{IP: "1.1.1.1", Password: "wrong"})
+	require.Equal(t, ErrWrongPassword, err)
+	r, err := s.Redirect(id, &Visit{IP: "1.1.1.1", Password: "s3cret"})
+	require.NoError(t, err)
+	require.Equal(t, "https://ilij.li/a", r.URL)
+	u, _ = s.Peek(id)
+	require.Equal(t, uint64(1), u.Counter)
+
+	// the failed attempts are limited per client
+	for i := 0; i < 2; i++ {
+		_, err = s.Redirect(id, &Visit{IP: "2.2.2.2", Password: "wrong"})
+		require.Equal(t, ErrWrongPassword, err)
+	}
+	_, err = s.Redirect(id, &Visit{IP: "2.2.2.2", Password: "s3cret"})
+	require.Equal(t, ErrTooManyAttempts, err)
+	_, err = s.Redirect(id, &Visit{IP: "3.3.3.3", Password: "s3cret"})
+	require.NoError(t, err)
+	// until the lockout is over
+	s.Config.ShortID.PasswordLockout = 0
+	s.pruneAttempts(time.Now())
+	require.Empty(t, s.attempts)
+	_, err = s.Redirect(id, &Visit{IP: "2.2.2.2", Password: "s3cret"})
+	require.NoError(t, err)
+
+	// patch
+	password := ""
+	u, err = s.PatchURL(id, &URLPatch{Password: &password}, "")
+	require.NoError(t, err)
+	require.False(t, u.Protected())
+	_, err = s.Redirect(id, nil)
+	require.NoError(t, err)
+}
+
+func TestPasswordAttempts(t *testing.T) {
+	t.Parallel()
+	cfg := buildConifgTest()
+	cfg.ShortID.PasswordMaxAttempts = 3
+	cfg.ShortID.PasswordMaxURLAttempts = 10
+	s := openBackendStore(t, BackendMemory, cfg)
+	defer s.Close()
+	id, err := s.UpsertURLSimple(&URLReq{URL: "https://ilij.li/a", Password: "s3cret"})
+	require.NoError(t, err)
+
+	// the parallel attempts of a client count against the limit
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		go func() {
+			_, err := s.Redirect(id, &Visit{IP: "1.1.1.1", Password: "wrong"})
+			errs <- err
+		}()
+	}
+	wrong := 0
+	for i := 0; i < 10; i++ {
+		if <-errs == ErrWrongPassword {
+			wrong++
+		}
+	}
+	require.Equal(t, 3, wrong)
+
+	// the right password does not use up the attempts of the url
+	for i := 0; i < 5; i++ {
+		_, err = s.Redirect(id, &Visit{IP: "2.2.2.2", Password: "s3cret"})
+		require.NoError(t, err)
+	}
+
+	// a client rotating the ip is limited by the attempts on the url
+	for i := 0; i < 7; i++ {
+		_, err = s.Redirect(id, &Visit{IP: fmt.Sprint("3.3.3.", i), Password: "wrong"})
+		require.Equal(t, ErrWrongPassword, err)
+	}
+	_, err = s.Redirect(id, &Visit{IP: "4.4.4.4", Password: "wrong"})
+	require.Equal(t, ErrTooManyAttempts, err)
+}
+
+func TestPasswordBackup(t *testing.T) {
+	t.Parallel()
+	tmpdir, _ := ioutil.TempDir("/tmp/", "distill-password")
+	defer os.RemoveAll(tmpdir)
+	bckFile := filepath.Join(tmpdir, "bck.csv")
+
+	cfg := buildConifgTest()
+	s := openBackendStore(t, BackendMemory, cfg)
+	id, err := s.UpsertURLSimple(&URLReq{URL: "https://ilij.li/a", Password: "s3cret"})
+	require.NoError(t, err)
+	u, _ := s.Peek(id)
+	hash := u.PasswordHash
+	require.NoError(t, s.Backup(bckFile))
+	s.Close()
+
+	// the hash is not in the backup in clear
+	raw, err := ioutil.ReadFile(bckFile)
+	require.NoError(t, err)
+	require.NotContains(t, string(raw), hash)
+	records, err := csv.NewReader(strings.NewReader(string(raw))).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.NotEmpty(t, records[0][recordPasswordIdx])
+
+	// restore with the same api key
+	cfg.Server.DbPath, _ = ioutil.TempDir(tmpdir, "db")
+	s = openBackendStore(t, BackendMemory, cfg)
+	n, err := s.Restore(bckFile)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	u, err = s.Peek(id)
+	require.NoError(t, err)
+	require.Equal(t, hash, u.PasswordHash)
+	_, err = s.Redirect(id, &Visit{Password: "s3cret"})
+	require.NoError(t, err)
+	s.Close()
+
+	// a different api key cannot restore the hash
+	cfg.Server.APIKey = "another"
+	s = openBackendStore(t, BackendMemory, cfg)
+	defer s.Close()
+	_, err = s.Restore(bckFile)
+	require.Equal(t, ErrInvalidSealedSecret, err)
+	_, err = s.Peek(id)
+	require.Error(t, err)
+}
@@ -0,0 +1,77 @@
+package urlstore
+
+import (
+	net "net/url"
+	"strings"
+
+	"github.com/noandrea/distill/pkg/useragent"
+)
+
+// ruleDevices are the device classes a rule can match
+var ruleDevices = []string{
+	useragent.DeviceDesktop,
+	useragent.DeviceMobile,
+	useragent.DeviceTablet,
+	useragent.DeviceTV,
+	useragent.DeviceConsole,
+	useragent.DeviceBot,
+	useragent.Unknown,
+}
+
+// validateRules checks that the rules have a valid url and at
+// least one condition, the device must be a known device class
+func validateRules(rules []*Rule) (err error) {
+	for _, r := range rules {
+		if r == nil || len(strings.TrimSpace(r.URL)) == 0 {
+			return ErrInvalidRule
+		}
+		if len(r.Device) == 0 && len(r.OS) == 0 && len(r.Bot) == 0 {
+			return ErrInvalidRule
+		}
+		if len(r.Device) > 0 && !containsFold(ruleDevices, r.Device) {
+			return ErrInvalidRule
+		}
+		if len(r.Bot) > 0 && !containsFold([]string{breakdownIsBot, breakdownIsHuman}, r.Bot) {
+			return ErrInvalidRule
+		}
+		if _, err = net.Parse(r.URL); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Match tells if the rule matches a classified user agent
+func (r *Rule) Match(ua useragent.UserAgent) bool {
+	if len(r.Device) > 0 && !strings.EqualFold(r.Device, ua.Device) {
+		return false
+	}
+	if len(r.OS) > 0 && !strings.EqualFold(r.OS, ua.OS) {
+		return false
+	}
+	if len(r.Bot) > 0 && strings.EqualFold(r.Bot, breakdownIsBot) != ua.Bot {
+		return false
+	}
+	return true
+}
+
+// matchRules returns the target of the first rule matching the user agent
+func matchRules(rules []*Rule, userAgent string) (target string, ok bool) {
+	ua := useragent.Parse(userAgent)
+	for _, r := range rules {
+		if r.Match(ua) {
+			return r.URL, true
+		}
+	}
+	return
+}
+
+// containsFold tells if the value is in the list, ignoring the case
+func containsFold(list []string, value string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package urlstore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	uaIPhone  = "Mozilla/5.0 (iPhone; CPU iPhone OS 13_3 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/13.0.4 Mobile/15E148 Safari/604.1"
+	uaAndroid = "Mozilla/5.0 (Linux; Android 10; SM-G973F) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/79.0.3945.93 Mobile Safari/537.36"
+	uaIPad    = "Mozilla/5.0 (iPad; CPU OS 13_3 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/13.0.4 Mobile/15E148 Safari/604.1"
+	uaWindows = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/79.0.3945.88 Safari/537.36"
+	uaBot     = "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"
+)
+
+func TestRules(t *testing.T) {
+	t.Parallel()
+	s := openBackendStore(t, BackendMemory, buildConifgTest())
+	defer s.Close()
+	rules := []*Rule{
+		{Bot: "bot", URL: "https://ilij.li/preview"},
+		{OS: "ios", Device: "mobile", URL: "https://apps.apple.com/app"},
+		{OS: "Android", URL: "https://play.google.com/store/apps"},
+		{Device: "tablet", URL: "https://ilij.li/tablet"},
+	}
+	id, err := s.UpsertURLSimple(&URLReq{URL: "https://ilij.li", Rules: rules})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		userAgent string
+		want      string
+	}{
+		{"iphone", uaIPhone, "https://apps.apple.com/app"},
+		{"android", uaAndroid, "https://play.google.com/store/apps"},
+		{"ipad", uaIPad, "https://ilij.li/tablet"},
+		{"desktop", uaWindows, "https://ilij.li"},
+		{"bot", uaBot, "https://ilij.li/preview"},
+		{"no user agent", "", "https://ilij.li/preview"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.GetURLRedirectVisit(id, &Visit{UserAgent: tt.userAgent})
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+	// without a visit the rules are not evaluated
+	got, err := s.GetURLRedirect(id)
+	require.NoError(t, err)
+	require.Equal(t, "https://ilij.li", got)
+
+	// the rules take precedence over the variants
+	_, err = s.PatchURL(id, &URLPatch{Variants: &[]*Variant{{URL: "https://ilij.li/a", Weight: 1}}}, "")
+	require.NoError(t, err)
+	got, err = s.GetURLRedirectVisit(id, &Visit{UserAgent: uaIPhone})
+	require.NoError(t, err)
+	require.Equal(t, "https://apps.apple.com/app", got)
+	got, err = s.GetURLRedirectVisit(id, &Visit{UserAgent: uaWindows})
+	require.NoError(t, err)
+	require.Equal(t, "https://ilij.li/a", got)
+
+	// invalid rules
+	for _, r := range []*Rule{
+		{URL: "https://ilij.li"},
+		{OS: "iOS"},
+		{Device: "phone", URL: "https://ilij.li"},
+		{Bot: "maybe", URL: "https://ilij.li"},
+	} {
+		_, err = s.UpsertURLSimple(&URLReq{URL: "https://ilij.li", Rules: []*Rule{r}})
+		require.Equal(t, ErrInvalidRule, err, "rule %v", r)
+	}
+}
@@ -0,0 +1,43 @@
+package urlstore
+
+import (
+	net "net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// validateSchedule checks that the changes have a time and a valid
+// url and returns them sorted by time
+func validateSchedule(changes []*Change) (schedule []*Change, err error) {
+	for _, c := range changes {
+		if c == nil || c.At.IsZero() || len(strings.TrimSpace(c.URL)) == 0 {
+			return nil, ErrInvalidChange
+		}
+		if _, err = net.Parse(c.URL); err != nil {
+			return nil, err
+		}
+		schedule = append(schedule, c)
+	}
+	sort.SliceStable(schedule, func(i, j int) bool { return schedule[i].At.Before(schedule[j].At) })
+	return
+}
+
+// Active tells if the url is active at the time now,
+// an url is not active before its ActiveFrom date
+func (u URLInfo) Active(now time.Time) bool {
+	return u.ActiveFrom.IsZero() || !now.Before(u.ActiveFrom)
+}
+
+// URLAt returns the url at the time now, the one of the
+// last scheduled change already applied or else the url
+func (u URLInfo) URLAt(now time.Time) string {
+	target := u.URL
+	for _, c := range u.Schedule {
+		if now.Before(c.At) {
+			break
+		}
+		target = c.URL
+	}
+	return target
+}
@@ -0,0 +1,88 @@
+package urlstore
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestURLInfo_URLAt(t *testing.T) {
+	t0 := time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+	u := URLInfo{
+		URL: "https://ex.com/register",
+		Schedule: []*Change{
+			{At: t0, URL: "https://ex.com/live"},
+			{At: t0.Add(time.Hour), URL: "https://ex.com/recap"},
+		},
+	}
+	tests := []struct {
+		name string
+		now  time.Time
+		want string
+	}{
+		{"before", t0.Add(-time.Second), "https://ex.com/register"},
+		{"at", t0, "https://ex.com/live"},
+		{"between", t0.Add(time.Minute), "https://ex.com/live"},
+		{"after", t0.Add(2 * time.Hour), "https://ex.com/recap"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, u.URLAt(tt.now))
+		})
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	t.Parallel()
+	cfg := buildConifgTest()
+	cfg.ShortID.InactiveRedirectURL = "https://ilij.li/soon"
+	s := openBackendStore(t, BackendMemory, cfg)
+	defer s.Close()
+
+	now := time.Now()
+	// not active yet
+	id, err := s.UpsertURLSimple(&URLReq{URL: "https://ilij.li/a", ActiveFrom: now.Add(time.Hour)})
+	require.NoError(t, err)
+	r, err := s.Redirect(id, nil)
+	require.Equal(t, ErrURLInactive, err)
+	require.Equal(t, Redirect{URL: "https://ilij.li/soon", Status: http.StatusFound}, r)
+	u, _ := s.Peek(id)
+	require.Equal(t, uint64(0), u.Counter)
+	require.Equal(t, URLStateInactive, u.State(now))
+	inactive := "https://ilij.li/wait"
+	_, err = s.PatchURL(id, &URLPatch{InactiveURL: &inactive}, "")
+	require.NoError(t, err)
+	r, _ = s.Redirect(id, nil)
+	require.Equal(t, inactive, r.URL)
+	active := now.Add(-time.Second)
+	_, err = s.PatchURL(id, &URLPatch{ActiveFrom: &active}, "")
+	require.NoError(t, err)
+	r, err = s.Redirect(id, nil)
+	require.NoError(t, err)
+	require.Equal(t, "https://ilij.li/a", r.URL)
+
+	// scheduled changes, sorted by time
+	id, err = s.UpsertURLSimple(&URLReq{URL: "https://ilij.li/register", Schedule: []*Change{
+		{At: now.Add(time.Hour), URL: "https://ilij.li/recap"},
+		{At: now.Add(-time.Hour), URL: "https://ilij.li/live"},
+	}})
+	require.NoError(t, err)
+	u, _ = s.Peek(id)
+	require.Equal(t, "https://ilij.li/live", u.Schedule[0].URL)
+	r, err = s.Redirect(id, nil)
+	require.NoError(t, err)
+	require.Equal(t, "https://ilij.li/live", r.URL)
+	schedule := []*Change{{At: now.Add(-time.Minute), URL: "https://ilij.li/recap"}}
+	_, err = s.PatchURL(id, &URLPatch{Schedule: &schedule}, "")
+	require.NoError(t, err)
+	r, _ = s.Redirect(id, nil)
+	require.Equal(t, "https://ilij.li/recap", r.URL)
+
+	// invalid changes
+	_, err = s.UpsertURLSimple(&URLReq{URL: "https://ilij.li/a", Schedule: []*Change{{URL: "https://ilij.li/b"}}})
+	require.Equal(t, ErrInvalidChange, err)
+	_, err = s.UpsertURLSimple(&URLReq{URL: "https://ilij.li/a", Schedule: []*Change{{At: now}}})
+	require.Equal(t, ErrInvalidChange, err)
+}
@@ -1,127 +1,73 @@
 package urlstore
 
 import (
-	"sync"
 	"time"
 
-	"github.com/bluele/gcache"
 	"github.com/jbrodriguez/mlog"
-
-	"github.com/dgraph-io/badger"
 )
 
 var (
-	wg               sync.WaitGroup
-	sc               gcache.Cache
-	opEventsQueue    chan *URLOp
-	globalStatistics *Statistics
 	// sytem keys
-	sysKeyPurgeCount []byte
-	sysKeyGCCount    []byte
+	sysKeyPurgeCount = keySys("distill_sys_purge_count")
+	sysKeyGCCount    = keySys("distill_sys_gc_count")
 )
 
 // pushEvent in the url operaiton queue
-func pushEvent(urlop *URLOp) {
-	s := Statistics{}
+func (s *Store) pushEvent(urlop *URLOp) {
+	st := Statistics{}
 	switch urlop.opcode {
 	case opcodeDelete:
-		s.Deletes++
-		s.Urls--
+		st.Deletes++
+		st.Urls--
+		s.metrics.deletes.Inc()
 	case opcodeInsert:
-		s.Upserts++
-		s.Urls++
+		st.Upserts++
+		st.Urls++
+		s.metrics.upserts.Inc()
+	case opcodeUpdate:
+		st.Upserts++
+		s.metrics.upserts.Inc()
 	case opcodeGet:
-		s.LastRequest = time.Now()
-		s.Gets++
+		st.LastRequest = time.Now()
+		st.Gets++
 	case opcodeExpired:
-		s.LastRequest = time.Now()
-		s.GetsExpired++
+		st.LastRequest = time.Now()
+		st.GetsExpired++
 	}
-	UpdateStats(s)
-}
-
-// Process is an implementation of wp.Job.Process()
-func processEvents(workerID int) {
-
-	for {
-		uo, isChannelOpen := <-opEventsQueue
-		if !isChannelOpen {
-			break
-		}
-		mlog.Trace(">>> Event pid: %v, opcode:%v  %v", workerID, opcodeToString(uo.opcode), uo.ID)
-		switch uo.opcode {
-		case opcodeGet:
-			globalStatistics.record(1, 0, 0, 0, 0)
-		case opcodeInsert:
-			// TODO: check if existed already
-			globalStatistics.record(0, 1, 0, 1, 0)
-		case opcodeDelete:
-			globalStatistics.record(0, 0, 1, -1, 0)
-		case opcodeExpired:
-			globalStatistics.record(0, 0, 0, 0, 1)
-		}
-		mlog.Trace("<<< Event pid: %v, opcode:%v  %v", workerID, opcodeToString(uo.opcode), uo.ID)
-	}
-	// complete task
-	mlog.Trace("Stop event processor id: %v", workerID)
-	wg.Done()
-
-}
-
-// runDbMaintenance
-var (
-	statsMutex sync.Mutex
-)
-
-func (s *Statistics) record(get, upsert, delete, urls, getExpired int64) {
-	statsMutex.Lock()
-	// this is confusing but actually correct
-	// if the input number is negative will work just the same
-	s.Gets += uint64(get)
-	s.GetsExpired += uint64(getExpired)
-	s.Upserts += uint64(upsert)
-	s.Deletes += uint64(delete)
-	s.Urls += uint64(urls)
-	statsMutex.Unlock()
+	s.UpdateStats(st)
 }
 
-// runDbMaintenance
-var (
-	maintenanceMutex   sync.Mutex
-	maintenanceRunning = false
-)
-
 // setRunMaintenance change maintenance status
-func setRunMaintenance(val bool) {
-	maintenanceMutex.Lock()
-	defer maintenanceMutex.Unlock()
-	maintenanceRunning = val
+func (s *Store) setRunMaintenance(val bool) {
+	s.maintenanceM.Lock()
+	defer s.maintenanceM.Unlock()
+	s.maintenanceRunning = val
 }
 
 // isMaintenanceRunning check if there is already a routine doing maintenance
-func isMaintenanceRunning() bool {
-	maintenanceMutex.Lock()
-	defer maintenanceMutex.Unlock()
-	return maintenanceRunning
+func (s *Store) isMaintenanceRunning() bool {
+	s.maintenanceM.Lock()
+	defer s.maintenanceM.Unlock()
+	return s.maintenanceRunning
 }
 
 // runDbMaintenance runs the database maintenance
 // TODO: add tests for this function
-func runDbMaintenance() {
-	if isMaintenanceRunning() {
+func (s *Store) runDbMaintenance() {
+	if s.isMaintenanceRunning() {
 		return
 	}
-	setRunMaintenance(true)
-	defer setRunMaintenance(false)
-	wg.Add(1)
-	defer wg.Done()
+	s.setRunMaintenance(true)
+	defer s.setRunMaintenance(false)
+	s.wg.Add(1)
+	defer s.wg.Done()
 
 	// caluclate if gc is necessary
-	deletes := globalStatistics.Deletes
-	gcLimit := Config.Tuning.DbGCDeletesCount
+	deletes := s.GetStats().Deletes
+	gcLimit := s.Config.Tuning.DbGCDeletesCount
 	gcCount := uint64(0)
 	// retrieve the gcCount from the db
-	db.View(func(txn *badger.Txn) (err error) {
+	s.db.View(func(txn Txn) (err error) {
 		gcCount = dbGetUint64(txn, sysKeyGCCount)
 		return
 	})
@@ -137,10 +83,11 @@ func runDbMaintenance() {
 
 		mlog.Info("")
 
-		db.RunValueLogGC(Config.Tuning.DbGCDiscardRation)
+		s.db.RunGC(s.Config.Tuning.DbGCDiscardRation)
+		s.metrics.gcRuns.Inc()
 		mlog.Info("End maintenance n %d for deletes %d > %d", gcCount, deletes-latestGC, gcLimit)
 		// update the gcCount
-		db.Update(func(txn *badger.Txn) (err error) {
+		s.db.Update(func(txn Txn) (err error) {
 			gcCount++
 			dbSetUint64(txn, sysKeyGCCount, gcCount)
 			return
@@ -8,6 +8,7 @@ import (
 )
 
 func Test_loadGlobalStatistics(t *testing.T) {
+	t.Parallel()
 	tests := []struct {
 		name    string
 		wantS   *Statistics
@@ -50,12 +51,12 @@ func Test_loadGlobalStatistics(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			buildConifgTestShortIDParams("abcdefghkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789", 6)
-			NewSession()
+			cfg := buildConifgTestShortIDParams("abcdefghkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789", 6)
+			s := openStore(t, cfg)
 			ids := []string{}
 			// run inserts
 			for i := uint64(0); i < tt.wantS.Upserts; i++ {
-				id, err := UpsertURL(&URLReq{URL: fmt.Sprint("http://distll.it/?long=", i)}, true, true, time.Now())
+				id, err := s.UpsertURL(&URLReq{URL: fmt.Sprint("http://distll.it/?long=", i)}, true, true, time.Now())
 				if err != nil {
 					t.Error(err)
 				}
@@ -63,18 +64,18 @@ func Test_loadGlobalStatistics(t *testing.T) {
 			}
 			// run deletes
 			for i := uint64(0); i < tt.wantS.Deletes; i++ {
-				DeleteURL(ids[i])
+				s.DeleteURL(ids[i])
 			}
 			ids = ids[tt.wantS.Deletes:]
 			// run gets
 			for i := uint64(0); i < tt.wantS.Gets; i++ {
-				GetURLRedirect(ids[i%tt.wantS.Urls])
+				s.GetURLRedirect(ids[i%tt.wantS.Urls])
 			}
-			CloseSession()
-			NewSession()
+			s.Close()
+			s = openStore(t, cfg)
 
-			err := LoadStats()
-			gotS := GetStats()
+			err := s.LoadStats()
+			gotS := s.GetStats()
 			if (err != nil) != tt.wantErr {
 				t.Errorf("loadGlobalStatistics() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -85,13 +86,95 @@ func Test_loadGlobalStatistics(t *testing.T) {
 
 			// also test reset
 			gotS.Deletes = 0
-			err = ResetStats()
+			err = s.ResetStats()
 			if err != nil {
 				t.Errorf("resetGlobalStatistics() error = %v, wantErr %v", err, false)
 				return
 			}
 
-			CloseSession()
+			s.Close()
 		})
 	}
 }
+
+func TestFlush(t *testing.T) {
+	t.Parallel()
+	cfg := buildConifgTest()
+	cfg.Tuning.FlushInterval = 1
+	s := openBackendStore(t, BackendMemory, cfg)
+	defer s.Close()
+	id, _ := s.UpsertURLSimple(&URLReq{URL: "https://ilij.li"})
+	for i := 0; i < 3; i++ {
+		s.GetURLRedirect(id)
+	}
+	// read the persisted version, bypassing the cache
+	stored := func() (u *URLInfo, gets uint64) {
+		u = &URLInfo{}
+		s.db.View(func(txn Txn) error {
+			k, _ := keyURL(id)
+			gets = dbGetUint64(txn, statsKeyGlobalGetCount)
+			return dbGetBin(txn, k, u)
+		})
+		return
+	}
+	u, gets := stored()
+	if u.Counter != 0 || gets != 0 {
+		t.Fatalf("counter persisted before the flush: %d, %d", u.Counter, gets)
+	}
+	time.Sleep(1500 * time.Millisecond)
+	u, gets = stored()
+	if u.Counter != 3 || gets != 3 {
+		t.Errorf("Flush() counter = %d, gets = %d, want 3", u.Counter, gets)
+	}
+}
+
+func TestGetStats(t *testing.T) {
+	t.Parallel()
+	s := openBackendStore(t, BackendMemory, buildConifgTest())
+	defer s.Close()
+	id, _ := s.UpsertURLSimple(&URLReq{URL: "https://ilij.li"})
+	st := s.GetStats()
+	// read while the redirects update the statistics
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 100; i++ {
+			s.GetURLRedirect(id)
+		}
+		close(done)
+	}()
+	for i := 0; i < 100; i++ {
+		_ = *s.GetStats()
+	}
+	<-done
+	if st.Gets != 0 {
+		t.Errorf("GetStats() copy changed, gets = %d", st.Gets)
+	}
+	if got := s.GetStats().Gets; got != 100 {
+		t.Errorf("GetStats() gets = %d, want 100", got)
+	}
+}
+
+func TestFlushDisabled(t *testing.T) {
+	t.Parallel()
+	cfg := buildConifgTest()
+	cfg.Tuning.FlushInterval = 0
+	cfg.Validate()
+	s := openBackendStore(t, BackendMemory, cfg)
+	id, _ := s.UpsertURLSimple(&URLReq{URL: "https://ilij.li"})
+	if _, err := s.GetURLRedirect(id); err != nil {
+		t.Fatal(err)
+	}
+	// the counters are flushed only on demand
+	u := &URLInfo{}
+	s.db.View(func(txn Txn) error {
+		k, _ := keyURL(id)
+		return dbGetBin(txn, k, u)
+	})
+	if u.Counter != 0 {
+		t.Errorf("counter persisted without a flush: %d", u.Counter)
+	}
+	// close waits for the flush routine
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
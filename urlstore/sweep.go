@@ -0,0 +1,158 @@
+package urlstore
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jbrodriguez/mlog"
+)
+
+const (
+	// SweepArchive moves the swept urls to the archive
+	SweepArchive = "archive"
+	// SweepDelete deletes the swept urls and their clicks
+	SweepDelete = "delete"
+)
+
+// endedAt returns when the url expired or was exhausted, the earliest
+// if both, ok is false if the url is still usable at the time now
+func (u URLInfo) endedAt(now time.Time) (t time.Time, ok bool) {
+	if !u.ExpireOn.IsZero() && now.After(u.ExpireOn) {
+		t, ok = u.ExpireOn, true
+	}
+	if idle := u.IdleExpireOn(); !idle.IsZero() && now.After(idle) && (!ok || idle.Before(t)) {
+		t, ok = idle, true
+	}
+	if l := u.RequestsLimit(); l > 0 && u.Counter >= l {
+		// the last visit is the one that exhausted the url
+		last := u.LastVisit
+		if last.IsZero() {
+			last = u.BountAt
+		}
+		if !ok || last.Before(t) {
+			t, ok = last, true
+		}
+	}
+	return
+}
+
+// Sweep archives or deletes, depending on sweep.mode, the urls expired or
+// exhausted for more than sweep.grace_period seconds at the time now and
+// returns their number. The database maintenance runs after the sweep
+func (s *Store) Sweep(now time.Time) (n int, err error) {
+	grace := time.Duration(s.Config.Sweep.GracePeriod) * time.Second
+	ended := func(u *URLInfo) bool {
+		t, ok := u.endedAt(now)
+		return ok && now.Sub(t) >= grace
+	}
+	// collect the ids first, the urls are moved outside of the iteration
+	ids := []string{}
+	i := s.NewURLIterator()
+	for i.HasNext() {
+		u, ierr := i.NextURL()
+		if ierr != nil {
+			i.Close()
+			return n, ierr
+		}
+		// the cache has the most recent counters
+		if uic, cerr := s.uc.GetIFPresent(u.ID); cerr == nil {
+			u = uic.(*URLInfo)
+		}
+		if ended(u) {
+			ids = append(ids, u.ID)
+		}
+	}
+	i.Close()
+
+	archive := strings.ToLower(s.Config.Sweep.Mode) == SweepArchive
+	for _, id := range ids {
+		swept, serr := s.sweepURL(id, archive, ended)
+		if serr != nil {
+			mlog.Warning("Error sweeping the url %s: %v", id, serr)
+			err = serr
+			continue
+		}
+		if swept {
+			n++
+			s.pushEvent(&URLOp{opcode: opcodeDelete, ID: id})
+		}
+	}
+	mlog.Trace("Sweep() %d urls", n)
+	if n > 0 {
+		s.runDbMaintenance()
+	}
+	return
+}
+
+// sweepURL moves an url to the archive or deletes it, the url is checked
+// again since it might have been changed or requested in the meantime
+func (s *Store) sweepURL(id string, archive bool, ended func(u *URLInfo) bool) (swept bool, err error) {
+	um := s.urlM.of(id)
+	um.Lock()
+	defer um.Unlock()
+	u, _, err := s.peek(id)
+	if err != nil {
+		// already deleted
+		return false, nil
+	}
+	if !ended(u) {
+		return
+	}
+	// removing the url from the cache persists its latest counters
+	s.uc.Remove(id)
+	err = s.db.Update(func(txn Txn) (err error) {
+		ku, err := keyURL(id)
+		if err != nil {
+			return
+		}
+		if archive {
+			ka, _ := keyArchive(id)
+			if err = dbSetBin(txn, ka, u); err != nil {
+				return
+			}
+		} else if err = dbDelClicks(txn, id); err != nil {
+			return
+		}
+		return txn.Delete(ku)
+	})
+	if err != nil {
+		return
+	}
+	if !archive {
+		s.dropClicks(id)
+	}
+	return true, nil
+}
+
+// PeekArchived retrieve an url from the archive
+func (s *Store) PeekArchived(id string) (u *URLInfo, err error) {
+	err = s.db.View(func(txn Txn) (err error) {
+		k, err := keyArchive(id)
+		if err != nil {
+			return
+		}
+		u = &URLInfo{}
+		return dbGetBin(txn, k, u)
+	})
+	return
+}
+
+// runSweep sweeps the expired urls every interval until the store is closed
+func (s *Store) runSweep(interval time.Duration) {
+	defer s.wg.Done()
+	if interval <= 0 {
+		return
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-t.C:
+			if _, err := s.Sweep(time.Now()); err != nil {
+				mlog.Warning("Error sweeping the expired urls: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package urlstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestURLInfo_endedAt(t *testing.T) {
+	t0 := time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+	now := t0.Add(24 * time.Hour)
+	tests := []struct {
+		name   string
+		u      URLInfo
+		want   time.Time
+		wantOk bool
+	}{
+		{"active", URLInfo{BountAt: t0, ExpireOn: now.Add(time.Hour)}, time.Time{}, false},
+		{"expired", URLInfo{BountAt: t0, ExpireOn: t0.Add(time.Hour)}, t0.Add(time.Hour), true},
+		{"idle", URLInfo{BountAt: t0, IdleTTL: 60, LastVisit: t0.Add(time.Hour)}, t0.Add(time.Hour + time.Minute), true},
+		{"exhausted", URLInfo{BountAt: t0, MaxRequests: 2, Counter: 2, LastVisit: t0.Add(time.Minute)}, t0.Add(time.Minute), true},
+		{"exhausted before visits", URLInfo{BountAt: t0, OneTime: true, Counter: 1}, t0, true},
+		{"earliest", URLInfo{BountAt: t0, ExpireOn: t0.Add(2 * time.Hour), MaxRequests: 1, Counter: 1, LastVisit: t0.Add(time.Hour)}, t0.Add(time.Hour), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.u.endedAt(now)
+			require.Equal(t, tt.wantOk, ok)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSweep(t *testing.T) {
+	t.Parallel()
+	cfg := buildConifgTest()
+	cfg.Sweep.GracePeriod = 600
+	cfg.Tuning.DbGCDeletesCount = 1
+	s := openBackendStore(t, BackendMemory, cfg)
+	defer s.Close()
+
+	past := time.Now().Add(-time.Hour)
+	active, err := s.UpsertURLSimple(&URLReq{URL: "https://ilij.li/active"})
+	require.NoError(t, err)
+	expired, err := s.UpsertURL(&URLReq{URL: "https://ilij.li/expired", TTL: 60}, false, false, past)
+	require.NoError(t, err)
+	grace, err := s.UpsertURL(&URLReq{URL: "https://ilij.li/grace", TTL: 3540}, false, false, past)
+	require.NoError(t, err)
+	exhausted, err := s.UpsertURLSimple(&URLReq{URL: "https://ilij.li/exhausted", OneTime: true})
+	require.NoError(t, err)
+	_, err = s.Redirect(exhausted, nil)
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), s.GetStats().Urls)
+
+	// the exhausted url is still in the grace period
+	n, err := s.Sweep(time.Now())
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	_, err = s.Peek(expired)
+	require.Error(t, err)
+	u, err := s.PeekArchived(expired)
+	require.NoError(t, err)
+	require.Equal(t, "https://ilij.li/expired", u.URL)
+	require.Equal(t, uint64(3), s.GetStats().Urls)
+
+	// the exhausted url is archived with its counter
+	n, err = s.Sweep(time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	u, err = s.PeekArchived(exhausted)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), u.Counter)
+	_, err = s.PeekArchived(grace)
+	require.NoError(t, err)
+	_, err = s.Peek(active)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), s.GetStats().Urls)
+	require.Equal(t, uint64(3), s.GetStats().Deletes)
+
+	// the archived urls are not listed and their ids are not reused
+	l, err := s.ListURLs(&URLFilter{}, "", 10)
+	require.NoError(t, err)
+	require.Len(t, l.URLs, 1)
+	s.db.View(func(txn Txn) (err error) {
+		require.True(t, idTaken(txn, expired))
+		require.False(t, idTaken(txn, "notused"))
+		// the deletes triggered the gc
+		require.Equal(t, uint64(1), dbGetUint64(txn, sysKeyGCCount))
+		return
+	})
+	_, err = s.UpsertURL(&URLReq{ID: expired, URL: "https://ilij.li/new"}, false, false, time.Now())
+	require.Equal(t, ErrURLArchived, err)
+	u, err = s.PeekArchived(expired)
+	require.NoError(t, err)
+	require.Equal(t, "https://ilij.li/expired", u.URL)
+}
+
+func TestSweepDelete(t *testing.T) {
+	t.Parallel()
+	cfg := buildConifgTest()
+	cfg.Sweep.Mode = SweepDelete
+	s := openBackendStore(t, BackendMemory, cfg)
+	defer s.Close()
+
+	id, err := s.UpsertURLSimple(&URLReq{URL: "https://ilij.li/a", MaxRequests: 1})
+	require.NoError(t, err)
+	_, err = s.Redirect(id, nil)
+	require.NoError(t, err)
+	require.NoError(t, s.Flush())
+	hasClicks := func() (found bool) {
+		s.db.View(func(txn Txn) (err error) {
+			it := txn.Iterate(keyClicks(clicksHour, id))
+			defer it.Close()
+			found = it.Next()
+			return
+		})
+		return
+	}
+	require.True(t, hasClicks())
+	n, err := s.Sweep(time.Now())
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	_, err = s.Peek(id)
+	require.Error(t, err)
+	_, err = s.PeekArchived(id)
+	require.Error(t, err)
+	// with its clicks
+	require.False(t, hasClicks())
+	require.Equal(t, uint64(0), s.GetStats().Urls)
+}
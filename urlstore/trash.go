@@ -0,0 +1,191 @@
+package urlstore
+
+import (
+	"time"
+
+	"github.com/jbrodriguez/mlog"
+)
+
+// trashPurgeInterval is how often the urls past the trash retention are purged
+const trashPurgeInterval = time.Hour
+
+// TrashURL moves an url to the trash, the url can be restored with
+// RestoreURL until it is purged after trash.retention days,
+// in the meantime its id is not used for new urls
+func (s *Store) TrashURL(id string) (err error) {
+	um := s.urlM.of(id)
+	um.Lock()
+	defer um.Unlock()
+	current, _, err := s.peek(id)
+	if err != nil {
+		return
+	}
+	// copy on write, the current one might be in use
+	u := *current
+	u.DeletedAt = time.Now()
+	// removing the url from the cache persists its latest counters
+	s.uc.Remove(id)
+	err = s.db.Update(func(txn Txn) (err error) {
+		ku, err := keyURL(id)
+		if err != nil {
+			return
+		}
+		kt, _ := keyTrash(id)
+		if err = dbSetBin(txn, kt, &u); err != nil {
+			return
+		}
+		return txn.Delete(ku)
+	})
+	if err != nil {
+		return
+	}
+	s.pushEvent(&URLOp{opcode: opcodeDelete, ID: id})
+	return
+}
+
+// RestoreURL moves an url from the trash back to the urls,
+// it fails with ErrURLExists if an url with the same id has
+// been created in the meantime
+func (s *Store) RestoreURL(id string) (u *URLInfo, err error) {
+	um := s.urlM.of(id)
+	um.Lock()
+	defer um.Unlock()
+	err = s.db.Update(func(txn Txn) (err error) {
+		kt, err := keyTrash(id)
+		if err != nil {
+			return
+		}
+		u = &URLInfo{}
+		if err = dbGetBin(txn, kt, u); err != nil {
+			return
+		}
+		ku, _ := keyURL(id)
+		if _, err = dbGet(txn, ku); err != ErrKeyNotFound {
+			if err == nil {
+				err = ErrURLExists
+			}
+			return
+		}
+		u.DeletedAt = time.Time{}
+		if err = dbSetBin(txn, ku, u); err != nil {
+			return
+		}
+		return txn.Delete(kt)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.pushEvent(&URLOp{opcode: opcodeInsert, ID: id})
+	return
+}
+
+// InTrash tells if the url with the id is in the trash
+func (s *Store) InTrash(id string) (found bool) {
+	s.db.View(func(txn Txn) (err error) {
+		k, err := keyTrash(id)
+		if err != nil {
+			return
+		}
+		_, err = dbGet(txn, k)
+		found = err == nil
+		return
+	})
+	return
+}
+
+// ListTrash returns a page of the urls in the trash sorted by id,
+// the cursor is the Next value of the previous page
+func (s *Store) ListTrash(cursor string, limit int) (l *URLList, err error) {
+	l = &URLList{URLs: []*URLInfo{}}
+	i := s.newIterator(keyTrashPrefix)
+	defer i.Close()
+	if len(cursor) > 0 {
+		if err = i.Seek(cursor); err != nil {
+			return nil, err
+		}
+	}
+	for i.HasNext() {
+		u, err := i.NextURL()
+		if err != nil {
+			return nil, err
+		}
+		if len(l.URLs) >= limit {
+			l.Next = u.ID
+			break
+		}
+		l.URLs = append(l.URLs, u)
+	}
+	return
+}
+
+// PurgeTrash deletes the urls in the trash for more than trash.retention
+// days at the time now, with their clicks, and returns their number
+func (s *Store) PurgeTrash(now time.Time) (n int, err error) {
+	before := now.AddDate(0, 0, -s.Config.Trash.Retention)
+	ids := []string{}
+	i := s.newIterator(keyTrashPrefix)
+	for i.HasNext() {
+		u, ierr := i.NextURL()
+		if ierr != nil {
+			i.Close()
+			return n, ierr
+		}
+		if u.DeletedAt.Before(before) {
+			ids = append(ids, u.ID)
+		}
+	}
+	i.Close()
+
+	for _, id := range ids {
+		purged := false
+		perr := s.db.Update(func(txn Txn) (err error) {
+			kt, err := keyTrash(id)
+			if err != nil {
+				return
+			}
+			// the url might have been restored in the meantime
+			if _, err = dbGet(txn, kt); err != nil {
+				if err == ErrKeyNotFound {
+					err = nil
+				}
+				return
+			}
+			if err = txn.Delete(kt); err != nil {
+				return
+			}
+			purged = true
+			return dbDelClicks(txn, id)
+		})
+		if perr != nil {
+			mlog.Warning("Error purging the url %s: %v", id, perr)
+			err = perr
+			continue
+		}
+		if purged {
+			s.dropClicks(id)
+			n++
+		}
+	}
+	mlog.Trace("PurgeTrash() %d urls", n)
+	if n > 0 {
+		s.runDbMaintenance()
+	}
+	return
+}
+
+// runPurgeTrash purges the trash every interval until the store is closed
+func (s *Store) runPurgeTrash(interval time.Duration) {
+	defer s.wg.Done()
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-t.C:
+			if _, err := s.PurgeTrash(time.Now()); err != nil {
+				mlog.Warning("Error purging the trash: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package urlstore
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrash(t *testing.T) {
+	t.Parallel()
+	cfg := buildConifgTest()
+	cfg.Trash.Retention = 10
+	s := openBackendStore(t, BackendMemory, cfg)
+	defer s.Close()
+
+	id, err := s.UpsertURLSimple(&URLReq{URL: "https://ilij.li/qr"})
+	require.NoError(t, err)
+	other, err := s.UpsertURLSimple(&URLReq{URL: "https://ilij.li/other"})
+	require.NoError(t, err)
+	_, err = s.Redirect(id, nil)
+	require.NoError(t, err)
+	require.NoError(t, s.Flush())
+
+	// the url is moved to the trash with its counter
+	require.NoError(t, s.TrashURL(id))
+	require.Equal(t, uint64(1), s.GetStats().Urls)
+	_, err = s.Peek(id)
+	require.Error(t, err)
+	_, err = s.Redirect(id, nil)
+	require.Error(t, err)
+	require.Error(t, s.TrashURL(id))
+	require.NoError(t, s.TrashURL(other))
+	l, err := s.ListTrash("", 1)
+	require.NoError(t, err)
+	require.Len(t, l.URLs, 1)
+	require.NotEmpty(t, l.Next)
+	l, err = s.ListTrash(l.Next, 1)
+	require.NoError(t, err)
+	require.Len(t, l.URLs, 1)
+	require.Empty(t, l.Next)
+	l, _ = s.ListTrash("", 10)
+	for _, u := range l.URLs {
+		require.False(t, u.DeletedAt.IsZero())
+	}
+
+	// the id stays reserved
+	s.db.View(func(txn Txn) (err error) {
+		require.True(t, idTaken(txn, id))
+		return
+	})
+
+	// restore
+	u, err := s.RestoreURL(id)
+	require.NoError(t, err)
+	require.True(t, u.DeletedAt.IsZero())
+	require.Equal(t, uint64(1), u.Counter)
+	require.Equal(t, uint64(1), s.GetStats().Urls)
+	r, err := s.Redirect(id, nil)
+	require.NoError(t, err)
+	require.Equal(t, "https://ilij.li/qr", r.URL)
+	series, err := s.ClickSeries(id, GranularityHour, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), series.Total)
+	_, err = s.RestoreURL(id)
+	require.Equal(t, ErrKeyNotFound, err)
+
+	// the id cannot be used for a new url
+	_, err = s.UpsertURL(&URLReq{ID: other, URL: "https://ilij.li/new"}, false, false, time.Now())
+	require.Equal(t, ErrURLTrashed, err)
+	// and an url with the same id prevents the restore
+	require.NoError(t, s.Upsert(&URLInfo{ID: other, URL: "https://ilij.li/new"}))
+	_, err = s.RestoreURL(other)
+	require.Equal(t, ErrURLExists, err)
+	require.NoError(t, s.Delete(other))
+
+	// purge after the retention
+	n, err := s.PurgeTrash(time.Now())
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+	n, err = s.PurgeTrash(time.Now().AddDate(0, 0, 11))
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	l, _ = s.ListTrash("", 10)
+	require.Empty(t, l.URLs)
+	_, err = s.RestoreURL(other)
+	require.Equal(t, ErrKeyNotFound, err)
+}
+
+func TestTrashBackup(t *testing.T) {
+	t.Parallel()
+	tmpdir, _ := ioutil.TempDir("/tmp/", "distill-trash")
+	defer os.RemoveAll(tmpdir)
+	bckFile := filepath.Join(tmpdir, "bck.csv")
+
+	cfg := buildConifgTest()
+	s := openBackendStore(t, BackendMemory, cfg)
+	live, err := s.UpsertURLSimple(&URLReq{URL: "https://ilij.li/live"})
+	require.NoError(t, err)
+	trashed, err := s.UpsertURLSimple(&URLReq{URL: "https://ilij.li/trashed"})
+	require.NoError(t, err)
+	require.NoError(t, s.TrashURL(trashed))
+	archived, err := s.UpsertURL(&URLReq{URL: "https://ilij.li/archived", TTL: 1}, false, false, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	n, err := s.Sweep(time.Now())
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	l, err := s.ListTrash("", 10)
+	require.NoError(t, err)
+	require.Len(t, l.URLs, 1)
+	deletedAt := l.URLs[0].DeletedAt
+	require.NoError(t, s.Backup(bckFile))
+	s.Close()
+
+	// the urls are restored in the same state
+	s = openBackendStore(t, BackendMemory, cfg)
+	defer s.Close()
+	n, err = s.Restore(bckFile)
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+	_, err = s.Peek(live)
+	require.NoError(t, err)
+	_, err = s.Peek(trashed)
+	require.Error(t, err)
+	require.True(t, s.InTrash(trashed))
+	l, err = s.ListTrash("", 10)
+	require.NoError(t, err)
+	require.Len(t, l.URLs, 1)
+	require.WithinDuration(t, deletedAt, l.URLs[0].DeletedAt, time.Second)
+	u, err := s.RestoreURL(trashed)
+	require.NoError(t, err)
+	require.Equal(t, "https://ilij.li/trashed", u.URL)
+	_, err = s.Peek(archived)
+	require.Error(t, err)
+	u, err = s.PeekArchived(archived)
+	require.NoError(t, err)
+	require.Equal(t, "https://ilij.li/archived", u.URL)
+
+	// an unknown state stops the restore
+	require.NoError(t, ioutil.WriteFile(bckFile, []byte("ab,https://ilij.li,,,,,,,,,,,,,,,,,,,,,,,,,,,,,gone\n"), 0600))
+	_, err = s.Restore(bckFile)
+	require.Error(t, err)
+}
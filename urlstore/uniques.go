@@ -0,0 +1,193 @@
+package urlstore
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"time"
+
+	"github.com/jbrodriguez/mlog"
+	"github.com/noandrea/distill/pkg/hll"
+)
+
+// visitorSaltLength is the length of the salt of the visitors hash
+const visitorSaltLength = 32
+
+var (
+	// the salt of the current day, followed by the day
+	sysKeyVisitorSalt = keySys("distill_sys_visitor_salt")
+	// global unique visitors
+	statsKeyGlobalUniques = keyGlobalStat("distill_global_uniques")
+)
+
+// visitorSalt returns the salt of the visitors hash for the day of now,
+// the salt is random and it is replaced every day so that the visitors
+// cannot be tracked across days
+func (s *Store) visitorSalt(now time.Time) []byte {
+	day := uint64(startOfDay(now).Unix())
+	s.saltM.Lock()
+	defer s.saltM.Unlock()
+	if s.saltDay == day {
+		return s.salt
+	}
+	err := s.db.Update(func(txn Txn) (err error) {
+		v, err := txn.Get(sysKeyVisitorSalt)
+		if err == nil && len(v) == visitorSaltLength+8 && atoi(v[visitorSaltLength:]) == day {
+			s.salt = v[:visitorSaltLength]
+			return
+		}
+		salt := make([]byte, visitorSaltLength)
+		if _, err = rand.Read(salt); err != nil {
+			return
+		}
+		s.salt = salt
+		return txn.Set(sysKeyVisitorSalt, append(append([]byte{}, salt...), itoa(day)...))
+	})
+	if err != nil {
+		mlog.Warning("Error rotating the visitor salt: %v", err)
+	}
+	s.saltDay = day
+	return s.salt
+}
+
+// visitorHash returns the hash of a visitor, that is the ip and the user agent
+func (s *Store) visitorHash(v *Visit, now time.Time) uint64 {
+	h := sha256.New()
+	h.Write(s.visitorSalt(now))
+	h.Write([]byte(v.IP))
+	h.Write([]byte{0})
+	h.Write([]byte(v.UserAgent))
+	return binary.BigEndian.Uint64(h.Sum(nil))
+}
+
+// recordUnique adds the visitor to the unique visitors of the url id
+// and to the global ones, the sketches are written to the storage on flush
+func (s *Store) recordUnique(id string, v *Visit, now time.Time) {
+	h := s.visitorHash(v, now)
+	s.clicksM.Lock()
+	sk, ok := s.uniques[id]
+	if !ok {
+		sk = hll.New()
+		s.uniques[id] = sk
+	}
+	sk.Add(h)
+	s.globalUniques.Add(h)
+	s.clicksM.Unlock()
+}
+
+// flushUniques merges the pending sketches in the stored ones,
+// they are kept for the next flush if the commit fails
+func (s *Store) flushUniques() (err error) {
+	s.clicksM.Lock()
+	uniques, global := s.uniques, s.globalUniques
+	s.uniques, s.globalUniques = make(map[string]*hll.Sketch), hll.New()
+	s.clicksM.Unlock()
+	if len(uniques) == 0 {
+		return
+	}
+	ids := make([]string, 0, len(uniques))
+	for id := range uniques {
+		ids = append(ids, id)
+	}
+	write := func(txn Txn, live func(string) bool, i int) error {
+		if !live(ids[i]) {
+			return nil
+		}
+		return dbMergeSketch(txn, keyClicks(clicksUniques, ids[i]), uniques[ids[i]])
+	}
+	flushed := 0
+	err = s.flushBatches(len(ids), write, func(from, to int) { flushed = to })
+	if err == nil {
+		err = s.db.Update(func(txn Txn) error {
+			return dbMergeSketch(txn, statsKeyGlobalUniques, global)
+		})
+	}
+	if err != nil {
+		// put the visitors not committed back for the next flush,
+		// merging a sketch again does not count them twice
+		s.clicksM.Lock()
+		for _, id := range ids[flushed:] {
+			if p, found := s.uniques[id]; found {
+				p.Merge(uniques[id])
+			} else {
+				s.uniques[id] = uniques[id]
+			}
+		}
+		s.globalUniques.Merge(global)
+		s.clicksM.Unlock()
+	}
+	return
+}
+
+// Uniques returns the estimated number of unique visitors of the url id
+func (s *Store) Uniques(id string) (n uint64, err error) {
+	if _, err = s.Peek(id); err != nil {
+		return
+	}
+	return s.estimateUniques(keyClicks(clicksUniques, id), func() *hll.Sketch { return s.uniques[id] })
+}
+
+// GlobalUniques returns the estimated number of unique visitors of all the urls
+func (s *Store) GlobalUniques() (n uint64, err error) {
+	return s.estimateUniques(statsKeyGlobalUniques, func() *hll.Sketch { return s.globalUniques })
+}
+
+// estimateUniques merges the sketch stored at k with the pending one and
+// returns its estimate, pending is called with the lock held
+func (s *Store) estimateUniques(k []byte, pending func() *hll.Sketch) (n uint64, err error) {
+	sk := hll.New()
+	err = s.db.View(func(txn Txn) error {
+		return dbGetSketch(txn, k, sk)
+	})
+	if err != nil {
+		return
+	}
+	s.clicksM.Lock()
+	if p := pending(); p != nil {
+		sk.Merge(p)
+	}
+	s.clicksM.Unlock()
+	return sk.Estimate(), nil
+}
+
+// rebuildGlobalUniques replaces the global unique visitors
+// with the union of the unique visitors of the urls
+func (s *Store) rebuildGlobalUniques() (err error) {
+	return s.db.Update(func(txn Txn) (err error) {
+		global := hll.New()
+		it := txn.Iterate([]byte{keyClicksPrefix, clicksUniques})
+		defer it.Close()
+		for it.Next() {
+			v, err := it.Value()
+			if err != nil {
+				return err
+			}
+			sk := hll.New()
+			if err = sk.UnmarshalBinary(v); err != nil {
+				mlog.Warning("Invalid unique visitors at %X: %v", it.Key(), err)
+				continue
+			}
+			global.Merge(sk)
+		}
+		return dbSetBin(txn, statsKeyGlobalUniques, global)
+	})
+}
+
+// dbGetSketch reads the sketch at k, a missing sketch is empty
+func dbGetSketch(txn Txn, k []byte, sk *hll.Sketch) (err error) {
+	err = dbGetBin(txn, k, sk)
+	if err == ErrKeyNotFound {
+		err = nil
+	}
+	return
+}
+
+// dbMergeSketch merges sk in the sketch stored at k
+func dbMergeSketch(txn Txn, k []byte, sk *hll.Sketch) (err error) {
+	stored := hll.New()
+	if err = dbGetSketch(txn, k, stored); err != nil {
+		return
+	}
+	stored.Merge(sk)
+	return dbSetBin(txn, k, stored)
+}
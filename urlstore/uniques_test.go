@@ -0,0 +1,74 @@
+package urlstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUniques(t *testing.T) {
+	t.Parallel()
+	s := openBackendStore(t, BackendMemory, buildConifgTest())
+	defer s.Close()
+	a, err := s.UpsertURLSimple(&URLReq{URL: "https://ilij.li/a"})
+	require.NoError(t, err)
+	b, err := s.UpsertURLSimple(&URLReq{URL: "https://ilij.li/b"})
+	require.NoError(t, err)
+	day := time.Date(2019, 12, 20, 10, 0, 0, 0, time.UTC)
+	alice := &Visit{IP: "10.0.0.1", UserAgent: "curl/7.64.1"}
+	bob := &Visit{IP: "10.0.0.2", UserAgent: "curl/7.64.1"}
+	carol := &Visit{IP: "10.0.0.1", UserAgent: "Wget/1.20.3"}
+
+	// the same visitor is counted once
+	s.recordUnique(a, alice, day)
+	s.recordUnique(a, alice, day.Add(time.Hour))
+	s.recordUnique(a, bob, day)
+	n, err := s.Uniques(a)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), n)
+	// the sketches are merged with the flushed ones
+	require.NoError(t, s.Flush())
+	s.recordUnique(a, bob, day)
+	s.recordUnique(a, carol, day)
+	s.recordUnique(b, alice, day)
+	n, err = s.Uniques(a)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), n)
+	n, err = s.Uniques(b)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), n)
+	n, err = s.GlobalUniques()
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), n)
+
+	// the salt changes the next day, the visitor is counted again
+	s.recordUnique(b, alice, day.Add(24*time.Hour))
+	require.NoError(t, s.Flush())
+	n, err = s.Uniques(b)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), n)
+	n, err = s.GlobalUniques()
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), n)
+
+	// the salt of the day is kept across restarts
+	s.saltDay = 0
+	s.recordUnique(b, alice, day.Add(24*time.Hour))
+	n, err = s.Uniques(b)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), n)
+
+	// deleted urls are removed from the global count after a reset
+	require.NoError(t, s.Flush())
+	require.NoError(t, s.DeleteURL(b))
+	_, err = s.Uniques(b)
+	require.Equal(t, ErrKeyNotFound, err)
+	require.NoError(t, s.ResetStats())
+	n, err = s.GlobalUniques()
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), n)
+
+	_, err = s.Uniques("notfound")
+	require.Equal(t, ErrKeyNotFound, err)
+}
@@ -4,14 +4,17 @@ package urlstore
 import (
 	"encoding/csv"
 	"fmt"
+	"hash/fnv"
 	"io"
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/bluele/gcache"
-	"github.com/dgraph-io/badger"
 	"github.com/jbrodriguez/mlog"
+	"github.com/noandrea/distill/pkg/hll"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 const (
@@ -27,64 +30,235 @@ var (
 	statsKeyGlobalUpdCount = keyGlobalStat("distill_global_upd_count")
 )
 
-var (
-	db  *badger.DB
-	uc  gcache.Cache
-	st  *Statistics
-	stM sync.Mutex
-)
+// urlLocksCount is the number of mutexes serializing the url updates
+const urlLocksCount = 256
+
+// urlLocks serialize the updates of the same url, the ids are spread
+// over a fixed number of mutexes so different urls rarely wait each other
+type urlLocks [urlLocksCount]sync.Mutex
+
+// of returns the mutex of the url with the id
+func (l *urlLocks) of(id string) *sync.Mutex {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return &l[h.Sum32()%urlLocksCount]
+}
+
+// Store is a url store, it holds the persistent storage,
+// the url cache and the statistics
+type Store struct {
+	// Config is the configuration of the store
+	Config ConfigSchema
+	db     Backend
+	uc     gcache.Cache
+	st     *Statistics
+	stM    sync.Mutex
+	// serialize the url updates
+	upM sync.Mutex
+	// serialize the counter updates of each url
+	urlM urlLocks
+	// ids with counters not yet persisted
+	dirty  map[string]bool
+	dirtyM sync.Mutex
+	// clicks, breakdown and unique visitors not yet persisted
+	clicks        map[clickKey]uint64
+	breakdown     map[breakdownKey]uint64
+	uniques       map[string]*hll.Sketch
+	globalUniques *hll.Sketch
+	clicksM       sync.Mutex
+	// salt of the visitors hash and its day
+	salt    []byte
+	saltDay uint64
+	saltM   sync.Mutex
+	// failed password attempts by url and client
+	attempts  map[string]*passwordAttempts
+	attemptsM sync.Mutex
+	stop      chan struct{}
+	// geo location of the visits, nil if disabled
+	geo GeoLocator
+	// metrics
+	registry *prometheus.Registry
+	metrics  *storeMetrics
+	// maintenance
+	wg                 sync.WaitGroup
+	maintenanceM       sync.Mutex
+	maintenanceRunning bool
+}
 
-//NewSession opens the underling storage
-func NewSession() {
-	// open the badger database
-	opts := badger.DefaultOptions(Config.Server.DbPath)
-	opts.SyncWrites = true
-	// opts.Dir = Config.Server.DbPath TODO: this should not be necessary anymore
-	err := os.MkdirAll(Config.Server.DbPath, os.ModePerm)
+// NewStore opens the storage backend selected in the configuration
+// and returns a store for it
+func NewStore(cfg ConfigSchema) (s *Store, err error) {
+	db, err := openBackend(cfg)
 	if err != nil {
-		mlog.Fatal(err)
+		return
 	}
-	opts.ValueDir = Config.Server.DbPath
-	db, err = badger.Open(opts)
-	if err != nil {
-		mlog.Fatal(err)
+	return NewStoreWithBackend(cfg, db)
+}
+
+// NewStoreWithBackend returns a store using the db backend,
+// the backend will be closed when the store is closed
+func NewStoreWithBackend(cfg ConfigSchema, db Backend) (s *Store, err error) {
+	s = &Store{
+		Config:        cfg,
+		db:            db,
+		dirty:         make(map[string]bool),
+		clicks:        make(map[clickKey]uint64),
+		breakdown:     make(map[breakdownKey]uint64),
+		uniques:       make(map[string]*hll.Sketch),
+		attempts:      make(map[string]*passwordAttempts),
+		globalUniques: hll.New(),
+		stop:          make(chan struct{}),
+		registry:      prometheus.NewRegistry(),
+	}
+	s.metrics = newStoreMetrics(s.registry, db)
+	if s.geo, err = openGeoLocator(cfg); err != nil {
+		s.db.Close()
+		return nil, err
 	}
 	// initialize internal cache
-	uc = gcache.New(Config.Tuning.URLCacheSize).
-		EvictedFunc(whenRemoved).
-		PurgeVisitorFunc(whenRemoved).
+	s.uc = gcache.New(cfg.Tuning.URLCacheSize).
+		EvictedFunc(s.whenRemoved).
+		PurgeVisitorFunc(s.whenRemoved).
 		ARC().
 		Build()
 	// initialize statistics
-	err = LoadStats()
+	if err = s.LoadStats(); err != nil {
+		s.db.Close()
+		return nil, err
+	}
+	// start the periodic flush
+	s.wg.Add(1)
+	go s.runFlush(time.Duration(cfg.Tuning.FlushInterval) * time.Second)
+	// start the clicks compaction
+	s.wg.Add(1)
+	go s.runCompactClicks(clicksCompactInterval)
+	// start the expired urls sweeper
+	s.wg.Add(1)
+	go s.runSweep(time.Duration(cfg.Sweep.Interval) * time.Second)
+	// start the trash purge
+	s.wg.Add(1)
+	go s.runPurgeTrash(trashPurgeInterval)
+	return
+}
+
+// Close flushes the pending changes and closes the underling storage
+func (s *Store) Close() (err error) {
+	close(s.stop)
+	s.wg.Wait()
+	s.Flush()
+	s.uc.Purge()
+	if s.geo != nil {
+		s.geo.Close()
+	}
+	return s.db.Close()
+}
+
+// runFlush flushes the pending changes every interval until the store is closed,
+// an interval of 0 disables the periodic flush, the changes are flushed on close
+func (s *Store) runFlush(interval time.Duration) {
+	defer s.wg.Done()
+	if interval <= 0 {
+		return
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-t.C:
+			if err := s.Flush(); err != nil {
+				mlog.Warning("Error flushing the url counters: %v", err)
+			}
+		}
+	}
+}
+
+// Flush writes the counters and the clicks of the urls requested
+// since the last flush and the global statistics to the storage
+func (s *Store) Flush() (err error) {
+	s.dirtyM.Lock()
+	ids := s.dirty
+	s.dirty = make(map[string]bool)
+	s.dirtyM.Unlock()
+	for id := range ids {
+		if uerr := s.flushURL(id); uerr != nil {
+			err = uerr
+		}
+	}
+	mlog.Trace("Flush() %d urls", len(ids))
+	if cerr := s.flushClicks(); cerr != nil {
+		err = cerr
+	}
+	if berr := s.flushBreakdown(); berr != nil {
+		err = berr
+	}
+	if uerr := s.flushUniques(); uerr != nil {
+		err = uerr
+	}
+	if serr := s.SaveStats(); serr != nil {
+		err = serr
+	}
+	s.pruneAttempts(time.Now())
+	return
+}
+
+// flushURL writes the counters of a cached url, holding the
+// lock of the url so that a concurrent update is not undone
+func (s *Store) flushURL(id string) (err error) {
+	um := s.urlM.of(id)
+	um.Lock()
+	defer um.Unlock()
+	uic, err := s.uc.GetIFPresent(id)
 	if err != nil {
-		mlog.Fatal(err)
+		// removed from the cache, already persisted or deleted
+		return nil
 	}
+	return s.persist(uic.(*URLInfo))
 }
 
-// CloseSession closes the underling storage
-func CloseSession() {
-	SaveStats()
-	uc.Purge()
-	db.Close()
+// persist writes an url only if it is still stored,
+// so a deleted, trashed or archived url is not written back
+func (s *Store) persist(u *URLInfo) (err error) {
+	return s.db.Update(func(txn Txn) (err error) {
+		key, err := keyURL(u.ID)
+		if err != nil {
+			return
+		}
+		if _, err = dbGet(txn, key); err != nil {
+			if err == ErrKeyNotFound {
+				err = nil
+			}
+			return
+		}
+		return dbSetBin(txn, key, u)
+	})
 }
 
 // whenRemoved gets called by the memory cache
 // it will check the value, if the value is nil
 // means that the key has been deleted
 // so it will delete it also from the persistent store
-func whenRemoved(key, value interface{}) {
+func (s *Store) whenRemoved(key, value interface{}) {
 	if value == nil {
-		Delete(key.(string))
+		s.Delete(key.(string))
 		return
 	}
 	ui := value.(*URLInfo)
-	Upsert(ui)
+	s.persist(ui)
 }
 
 // SaveStats write the URL's statistics
-func SaveStats() (err error) {
-	err = db.Update(func(txn *badger.Txn) (err error) {
+func (s *Store) SaveStats() (err error) {
+	s.stM.Lock()
+	st := *s.st
+	s.stM.Unlock()
+	return s.saveStats(st)
+}
+
+// saveStats write a copy of the statistics
+func (s *Store) saveStats(st Statistics) (err error) {
+	err = s.db.Update(func(txn Txn) (err error) {
 		// find all the urls
 		dbSetUint64(txn, statsKeyGlobalURLCount, st.Urls)
 		dbSetUint64(txn, statsKeyGlobalGetCount, st.Gets)
@@ -97,84 +271,102 @@ func SaveStats() (err error) {
 }
 
 // LoadStats write the URL's statistics
-func LoadStats() (err error) {
+func (s *Store) LoadStats() (err error) {
 	// initialize object
-	st = &Statistics{}
-	err = db.View(func(txn *badger.Txn) (err error) {
-		st.Urls = dbGetUint64(txn, statsKeyGlobalURLCount)
-		st.Gets = dbGetUint64(txn, statsKeyGlobalGetCount)
-		st.Deletes = dbGetUint64(txn, statsKeyGlobalDelCount)
-		st.Upserts = dbGetUint64(txn, statsKeyGlobalUpdCount)
+	s.st = &Statistics{}
+	err = s.db.View(func(txn Txn) (err error) {
+		s.st.Urls = dbGetUint64(txn, statsKeyGlobalURLCount)
+		s.st.Gets = dbGetUint64(txn, statsKeyGlobalGetCount)
+		s.st.Deletes = dbGetUint64(txn, statsKeyGlobalDelCount)
+		s.st.Upserts = dbGetUint64(txn, statsKeyGlobalUpdCount)
 		return
 	})
 	return
 }
 
 // UpdateStats uppdate urls statistics
-func UpdateStats(s Statistics) {
-	stM.Lock()
-	defer stM.Unlock()
-	st.Urls += s.Urls
-	st.Gets += s.Gets
-	st.Deletes += s.Deletes
-	st.Upserts += s.Upserts
-	st.GetsExpired += s.GetsExpired
-	st.LastRequest = s.LastRequest
+func (s *Store) UpdateStats(u Statistics) {
+	s.stM.Lock()
+	defer s.stM.Unlock()
+	s.st.Urls += u.Urls
+	s.st.Gets += u.Gets
+	s.st.Deletes += u.Deletes
+	s.st.Upserts += u.Upserts
+	s.st.GetsExpired += u.GetsExpired
+	s.st.LastRequest = u.LastRequest
 }
 
 // ResetStats reset global statistcs
-func ResetStats() (err error) {
-	stM.Lock()
-	defer stM.Unlock()
-	st = &Statistics{}
+func (s *Store) ResetStats() (err error) {
+	s.stM.Lock()
+	defer s.stM.Unlock()
+	s.st = &Statistics{}
 	// iterate over the urls
-	i := NewURLIterator()
+	i := s.NewURLIterator()
 	for i.HasNext() {
 		u, err := i.NextURL()
 		if err != nil {
 			mlog.Warning("Warning looping through the URLs")
 		}
-		st.Urls++
-		st.Upserts++
-		st.Gets += u.Counter
+		s.st.Urls++
+		s.st.Upserts++
+		s.st.Gets += u.Counter
 	}
 	// close the iterator
 	i.Close()
-	// run the update
-	err = SaveStats()
+	if err = s.rebuildGlobalUniques(); err != nil {
+		mlog.Warning("Error while rebuilding the unique visitors %v", err)
+	}
+	// run the update, the lock is already held
+	err = s.saveStats(*s.st)
 	if err != nil {
 		mlog.Warning("Error while rest stats %v", err)
 	}
 	return
 }
 
-// GetStats get the statistics
-func GetStats() (s *Statistics) {
-	return st
+// GetStats get a copy of the statistics
+func (s *Store) GetStats() *Statistics {
+	s.stM.Lock()
+	defer s.stM.Unlock()
+	st := *s.st
+	return &st
 }
 
-// Insert an url into the url store
-func Insert(u *URLInfo) (err error) {
-	err = db.Update(func(txn *badger.Txn) (err error) {
-		u.ID = generateID()
-		// generateID always return a valid id
-		key, _ := keyURL(u.ID)
+// Insert an url into the url store, the ids of the
+// archived and deleted urls are not used for the new urls
+func (s *Store) Insert(u *URLInfo) (err error) {
+	err = s.db.Update(func(txn Txn) (err error) {
+		u.ID = s.generateID()
 		// TODO: need another limit (numeber of retries)
-		// TODO: also check the type of error
-		for _, err = dbGet(txn, key); err == nil; {
-			u.ID = generateID()
-			// generateID always return a valid id
-			key, _ = keyURL(u.ID)
+		for idTaken(txn, u.ID) {
+			u.ID = s.generateID()
 		}
+		// generateID always return a valid id
+		key, _ := keyURL(u.ID)
 		err = dbSetBin(txn, key, u)
 		return
 	})
 	return err
 }
 
+// idTaken tells if the id is used by an url, an archived url or an url in the trash
+func idTaken(txn Txn, id string) bool {
+	for _, kf := range []func(string) ([]byte, error){keyURL, keyArchive, keyTrash} {
+		k, err := kf(id)
+		if err != nil {
+			return true
+		}
+		if _, err = dbGet(txn, k); err != ErrKeyNotFound {
+			return true
+		}
+	}
+	return false
+}
+
 // Upsert an url into the the urlstore
-func Upsert(u *URLInfo) (err error) {
-	err = db.Update(func(txn *badger.Txn) (err error) {
+func (s *Store) Upsert(u *URLInfo) (err error) {
+	err = s.db.Update(func(txn Txn) (err error) {
 		key, err := keyURL(u.ID)
 		if err != nil {
 			return
@@ -185,12 +377,40 @@ func Upsert(u *URLInfo) (err error) {
 	return err
 }
 
-// Peek retrive a url without incrementing the counter
-func Peek(id string) (u *URLInfo, err error) {
-	uic, err := uc.Get(id)
+// replace writes an url over the stored one, holding the lock of the url,
+// and updates the cached copy so it is not flushed over the new one
+func (s *Store) replace(u *URLInfo) (err error) {
+	um := s.urlM.of(u.ID)
+	um.Lock()
+	defer um.Unlock()
+	if err = s.Upsert(u); err != nil {
+		return
+	}
+	if s.uc.Has(u.ID) {
+		s.uc.Set(u.ID, u)
+	}
+	return
+}
+
+// Peek retrive a url without incrementing the counter,
+// the lookup is counted in the cache metrics
+func (s *Store) Peek(id string) (u *URLInfo, err error) {
+	u, cached, err := s.peek(id)
+	if cached {
+		s.metrics.cacheHits.Inc()
+	} else {
+		s.metrics.cacheMisses.Inc()
+	}
+	return
+}
+
+// peek retrieve an url from the cache or from the storage, without
+// counting the lookup, for the lookups following the one of a request
+func (s *Store) peek(id string) (u *URLInfo, cached bool, err error) {
+	uic, err := s.uc.Get(id)
 	if err == gcache.KeyNotFoundError {
 		mlog.Trace("cache miss for %s", id)
-		err = db.View(func(txn *badger.Txn) (err error) {
+		err = s.db.View(func(txn Txn) (err error) {
 			u = &URLInfo{}
 			ku, err := keyURL(id)
 			if err != nil {
@@ -203,44 +423,81 @@ func Peek(id string) (u *URLInfo, err error) {
 			return
 		})
 	} else {
+		cached = true
 		u = uic.(*URLInfo)
 	}
 	return
 }
 
-// Get an url from the datastore
-func Get(id string) (u *URLInfo, err error) {
-	u, err = Peek(id)
+// Get an url from the datastore and increase its counter.
+// The counter is increased atomically, holding only the lock of the
+// url, and the url returned is not modified by the following requests,
+// the one time urls are written to the storage right away
+func (s *Store) Get(id string) (u *URLInfo, err error) {
+	um := s.urlM.of(id)
+	um.Lock()
+	defer um.Unlock()
+	current, _, err := s.peek(id)
 	if err != nil {
 		return
 	}
-	// increase the counter
+	// copy on write, the current one might be in use
+	nu := *current
+	u = &nu
+	now := time.Now()
+	u.rollWindow(now)
+	// a successful redirect extends the idle expiration
+	if u.State(now) == URLStateActive {
+		u.LastVisit = now
+	}
 	u.Counter++
-	uc.Set(id, u)
+	if u.RateLimited() {
+		u.WindowCounter++
+	}
+	if u.OneTime {
+		if err = s.Upsert(u); err != nil {
+			return nil, err
+		}
+	} else {
+		s.dirtyM.Lock()
+		s.dirty[id] = true
+		s.dirtyM.Unlock()
+	}
+	s.uc.Set(id, u)
+	s.recordClick(id, now)
 	return
 }
 
 // Delete deletes an url
-func Delete(id string) (err error) {
-	err = db.Update(func(txn *badger.Txn) (err error) {
-		// remove from cache
-		uc.Remove(id)
+func (s *Store) Delete(id string) (err error) {
+	// a concurrent request must not put the url back in the cache
+	um := s.urlM.of(id)
+	um.Lock()
+	defer um.Unlock()
+	// remove from cache, outside of the transaction since
+	// the eviction callback writes to the storage
+	s.uc.Remove(id)
+	err = s.db.Update(func(txn Txn) (err error) {
 		// remove from storage
 		key, err := keyURL(id)
 		if err != nil {
 			return
 		}
 		// then delete the keys
-		err = txn.Delete(key)
+		if err = txn.Delete(key); err != nil {
+			return
+		}
+		err = dbDelClicks(txn, id)
 		mlog.Trace("Delete() 01 %v", err)
 		return err
 	})
+	s.dropClicks(id)
 	mlog.Trace("Delete() 02 %v", err)
 	return
 }
 
 // Backup the database as csv
-func Backup(outFile string) (err error) {
+func (s *Store) Backup(outFile string) (err error) {
 	ext := filepath.Ext(outFile)
 	switch ext {
 	case backupExtBin:
@@ -249,13 +506,13 @@ func Backup(outFile string) (err error) {
 		if err != nil {
 			return err
 		}
-		ts, err := db.Backup(fp, 0)
-		if err != nil {
+		defer fp.Close()
+		if err = s.db.Backup(fp); err != nil {
 			return err
 		}
-		mlog.Info("Backup completed at %v", ts)
+		mlog.Info("Backup completed to %v", outFile)
 	case backupExtCsv:
-		err = db.View(func(txn *badger.Txn) (err error) {
+		err = s.db.View(func(txn Txn) (err error) {
 			// create output file
 			fp, err := os.Create(outFile)
 			if err != nil {
@@ -266,23 +523,10 @@ func Backup(outFile string) (err error) {
 			csvW := csv.NewWriter(fp)
 			defer csvW.Flush()
 
-			// open the iterator
-			opts := badger.DefaultIteratorOptions
-			opts.PrefetchSize = Config.Tuning.BckCSVIterPrefetchSize
-			opts.PrefetchValues = true
-			it := txn.NewIterator(opts)
-			defer it.Close()
-
-			p := []byte{keyURLPrefix}
-			for it.Seek(p); it.ValidForPrefix(p); it.Next() {
-				// retrieve values
-				err := it.Item().Value(func(v []byte) error {
-					u := &URLInfo{}
-					u.UnmarshalBinary(v)
-					return csvW.Write(u.MarshalRecord())
-				})
-				if err != nil {
-					break
+			// the live urls, then the archived and the trashed ones
+			for _, rs := range recordStates {
+				if err = s.backupRecords(txn, csvW, rs.prefix, rs.state); err != nil {
+					return
 				}
 			}
 			return
@@ -294,8 +538,55 @@ func Backup(outFile string) (err error) {
 	return
 }
 
+// backupRecords writes the urls stored with a key prefix to a csv backup
+func (s *Store) backupRecords(txn Txn, csvW *csv.Writer, prefix byte, state string) (err error) {
+	it := txn.Iterate([]byte{prefix})
+	defer it.Close()
+	for it.Next() {
+		// retrieve values
+		v, err := it.Value()
+		if err != nil {
+			return err
+		}
+		u := &URLInfo{}
+		if err = u.UnmarshalBinary(v); err != nil {
+			return err
+		}
+		record := u.MarshalRecord()
+		if record[recordPasswordIdx], err = s.sealSecret(u.PasswordHash); err != nil {
+			return err
+		}
+		record[recordStateIdx] = state
+		if err = csvW.Write(record); err != nil {
+			return err
+		}
+	}
+	return
+}
+
+// restoreRecord stores an url read from a csv backup
+// in the archive, in the trash or with the live urls
+func (s *Store) restoreRecord(u *URLInfo, state string) (err error) {
+	for _, rs := range recordStates {
+		if rs.state != state {
+			continue
+		}
+		if rs.prefix == keyURLPrefix {
+			return s.replace(u)
+		}
+		return s.db.Update(func(txn Txn) (err error) {
+			k, err := key(rs.prefix, u.ID)
+			if err != nil {
+				return
+			}
+			return dbSetBin(txn, k, u)
+		})
+	}
+	return fmt.Errorf("Unrecognized url state %v", state)
+}
+
 // Restore the database from a backup file
-func Restore(inFile string) (count int, err error) {
+func (s *Store) Restore(inFile string) (count int, err error) {
 	ext := filepath.Ext(inFile)
 	switch ext {
 	case backupExtBin:
@@ -303,8 +594,11 @@ func Restore(inFile string) (count int, err error) {
 		if err != nil {
 			return 0, err
 		}
-		db.Load(fp, 16)
+		err = s.db.Load(fp)
 		fp.Close()
+		if err != nil {
+			return 0, err
+		}
 	case backupExtCsv:
 		fp, err := os.Open(inFile)
 		if err != nil {
@@ -312,8 +606,10 @@ func Restore(inFile string) (count int, err error) {
 		}
 		csvR := csv.NewReader(fp)
 		for {
-			record, err := csvR.Read()
+			var record []string
+			record, err = csvR.Read()
 			if err == io.EOF {
+				err = nil
 				break
 			}
 			if err != nil {
@@ -323,12 +619,25 @@ func Restore(inFile string) (count int, err error) {
 			if err = u.UnmarshalRecord(record); err != nil {
 				break
 			}
-			if err = Upsert(u); err != nil {
+			if len(record) > recordPasswordIdx {
+				if u.PasswordHash, err = s.openSecret(record[recordPasswordIdx]); err != nil {
+					break
+				}
+			}
+			var state string
+			if len(record) > recordStateIdx {
+				state = record[recordStateIdx]
+			}
+			if err = s.restoreRecord(u, state); err != nil {
 				break
 			}
 			count++
 		}
 		fp.Close()
+		// a record that cannot be restored stops the restore
+		if err != nil {
+			return count, err
+		}
 	default:
 		err = fmt.Errorf("Unrecoginzed backup format %v", ext)
 		mlog.Warning("Unrecoginzed backup format %v", ext)
@@ -337,37 +646,51 @@ func Restore(inFile string) (count int, err error) {
 }
 
 // NewURLIterator return an url iterator over the database
-func NewURLIterator() *URLIterator {
-	txn := db.NewTransaction(false)
-	it := txn.NewIterator(badger.DefaultIteratorOptions)
-	px := []byte{keyURLPrefix}
-	it.Seek(px)
+func (s *Store) NewURLIterator() *URLIterator {
+	return s.newIterator(keyURLPrefix)
+}
+
+// newIterator return an url iterator over the urls stored with prefix
+func (s *Store) newIterator(prefix byte) *URLIterator {
+	txn := s.db.NewTransaction(false)
 	return &URLIterator{
 		Transaction: txn,
-		Iterator:    it,
-		KeyPrefix:   px,
+		Iterator:    txn.Iterate([]byte{prefix}),
+		prefix:      prefix,
 	}
 }
 
 // URLIterator an iterator over URLs
 type URLIterator struct {
-	Transaction *badger.Txn
-	Iterator    *badger.Iterator
-	KeyPrefix   []byte
+	Transaction Txn
+	Iterator    Iterator
+	prefix      byte
 }
 
-// HasNext tells if there are still elements in the list
+// HasNext moves to the next element and tells if there is one
 func (i *URLIterator) HasNext() bool {
-	i.Iterator.Next()
-	return i.Iterator.ValidForPrefix(i.KeyPrefix)
+	return i.Iterator.Next()
+}
+
+// Seek moves the iterator so that the next call to HasNext
+// moves to the url with the id or the one that follows it
+func (i *URLIterator) Seek(id string) (err error) {
+	k, err := key(i.prefix, id)
+	if err != nil {
+		return
+	}
+	i.Iterator.Seek(k)
+	return
 }
 
-// NextURL get the next URL from the iterator
+// NextURL get the current URL from the iterator
 func (i *URLIterator) NextURL() (u *URLInfo, err error) {
+	v, err := i.Iterator.Value()
+	if err != nil {
+		return
+	}
 	u = &URLInfo{}
-	err = i.Iterator.Item().Value(func(v []byte) error {
-		return u.UnmarshalBinary(v)
-	})
+	err = u.UnmarshalBinary(v)
 	return
 }
 
@@ -380,7 +703,7 @@ func (i *URLIterator) Close() {
 // Helper functions
 
 // dbGet helper functin
-func dbDel(txn *badger.Txn, keys ...[]byte) (err error) {
+func dbDel(txn Txn, keys ...[]byte) (err error) {
 	for _, k := range keys {
 		err = txn.Delete(k)
 		mlog.Trace("dbDel write %s", k)
@@ -388,7 +711,7 @@ func dbDel(txn *badger.Txn, keys ...[]byte) (err error) {
 	return
 }
 
-func dbSetUint64(txn *badger.Txn, k []byte, val uint64) {
+func dbSetUint64(txn Txn, k []byte, val uint64) {
 	err := txn.Set(k, itoa(val))
 	mlog.Trace("dbSetInt64 write %s", k)
 	if err != nil {
@@ -396,7 +719,7 @@ func dbSetUint64(txn *badger.Txn, k []byte, val uint64) {
 	}
 }
 
-func dbSetBin(txn *badger.Txn, k []byte, val BinSerializable) (err error) {
+func dbSetBin(txn Txn, k []byte, val BinSerializable) (err error) {
 	binData, err := val.MarshalBinary()
 	if err != nil {
 		return
@@ -406,35 +729,23 @@ func dbSetBin(txn *badger.Txn, k []byte, val BinSerializable) (err error) {
 	return
 }
 
-func dbGet(txn *badger.Txn, k []byte) (val []byte, err error) {
-	item, err := txn.Get(k)
-	if err != nil {
-		return
-	}
-	err = item.Value(func(v []byte) error {
-		val = v
-		return nil
-	})
-	mlog.Trace("dbGet read %s v:%d ", k, item.Version())
+func dbGet(txn Txn, k []byte) (val []byte, err error) {
+	val, err = txn.Get(k)
+	mlog.Trace("dbGet read %s", k)
 	return
 }
 
-func dbGetUint64(txn *badger.Txn, k []byte) (i uint64) {
-	item, err := txn.Get(k)
+func dbGetUint64(txn Txn, k []byte) (i uint64) {
+	val, err := txn.Get(k)
 	if err != nil {
 		return
 	}
-	val := numberZero
-	err = item.Value(func(v []byte) error {
-		val = v
-		return nil
-	})
-	mlog.Trace("dbGetInt64 read %s v:%d ", k, item.Version())
+	mlog.Trace("dbGetInt64 read %s", k)
 	i = atoi(val)
 	return
 }
 
-func dbGetBin(txn *badger.Txn, k []byte, val BinSerializable) (err error) {
+func dbGetBin(txn Txn, k []byte, val BinSerializable) (err error) {
 	v, err := dbGet(txn, k)
 	if err != nil {
 		return err
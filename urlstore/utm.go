@@ -0,0 +1,124 @@
+package urlstore
+
+import (
+	"net/url"
+	"strings"
+)
+
+// utmParams are the names of the utm parameters,
+// in the order they are added to the targets
+var utmParams = []string{"utm_source", "utm_medium", "utm_campaign", "utm_term", "utm_content"}
+
+// UTMParams are the utm parameters added to the targets of an url,
+// for an url or for a campaign preset in the configuration
+type UTMParams struct {
+	Source   string `json:"utm_source,omitempty" yaml:"utm_source,omitempty" mapstructure:"utm_source"`
+	Medium   string `json:"utm_medium,omitempty" yaml:"utm_medium,omitempty" mapstructure:"utm_medium"`
+	Campaign string `json:"utm_campaign,omitempty" yaml:"utm_campaign,omitempty" mapstructure:"utm_campaign"`
+	Term     string `json:"utm_term,omitempty" yaml:"utm_term,omitempty" mapstructure:"utm_term"`
+	Content  string `json:"utm_content,omitempty" yaml:"utm_content,omitempty" mapstructure:"utm_content"`
+}
+
+// fields returns the pointers to the parameters, in the order of utmParams
+func (p *UTMParams) fields() []*string {
+	return []*string{&p.Source, &p.Medium, &p.Campaign, &p.Term, &p.Content}
+}
+
+// IsEmpty tells if no parameter is set
+func (p UTMParams) IsEmpty() bool {
+	return p == UTMParams{}
+}
+
+// Query encodes the parameters as a query string, in the order of utmParams
+func (p UTMParams) Query() string {
+	pieces := []string{}
+	for i, v := range p.fields() {
+		if len(*v) > 0 {
+			pieces = append(pieces, utmParams[i]+"="+url.QueryEscape(*v))
+		}
+	}
+	return strings.Join(pieces, "&")
+}
+
+// parseUTMQuery decodes a query string of utm parameters,
+// other parameters or repeated ones are invalid
+func parseUTMQuery(query string) (p UTMParams, err error) {
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return p, ErrInvalidUTM
+	}
+	fields := p.fields()
+	for k, v := range values {
+		i := indexOf(utmParams, k)
+		if i < 0 || len(v) != 1 {
+			return p, ErrInvalidUTM
+		}
+		*fields[i] = v[0]
+	}
+	return
+}
+
+// UTM returns the utm parameters of the url
+func (u *URLInfo) UTM() UTMParams {
+	return UTMParams{
+		Source:   u.UTMSource,
+		Medium:   u.UTMMedium,
+		Campaign: u.UTMCampaign,
+		Term:     u.UTMTerm,
+		Content:  u.UTMContent,
+	}
+}
+
+// SetUTM sets the utm parameters of the url
+func (u *URLInfo) SetUTM(p UTMParams) {
+	u.UTMSource, u.UTMMedium, u.UTMCampaign, u.UTMTerm, u.UTMContent = p.Source, p.Medium, p.Campaign, p.Term, p.Content
+}
+
+// validateCampaign checks that the campaign is empty or defined in the configuration
+func (s *Store) validateCampaign(name string) error {
+	if _, ok := s.Config.Campaigns[name]; len(name) > 0 && !ok {
+		return ErrInvalidCampaign
+	}
+	return nil
+}
+
+// UTM returns the utm parameters added to the targets of an url,
+// the ones of its campaign preset overridden by the ones of the url
+func (s *Store) UTM(u *URLInfo) (p UTMParams) {
+	if len(u.Campaign) > 0 {
+		p = s.Config.Campaigns[u.Campaign]
+	}
+	own := u.UTM()
+	fields := p.fields()
+	for i, v := range own.fields() {
+		if len(*v) > 0 {
+			*fields[i] = *v
+		}
+	}
+	return
+}
+
+// tag adds the utm parameters of an url to a target,
+// the parameters already in the target are not overwritten
+func (s *Store) tag(u *URLInfo, target string) string {
+	p := s.UTM(u)
+	if p.IsEmpty() {
+		return target
+	}
+	t, err := url.Parse(target)
+	if err != nil {
+		return target
+	}
+	t.RawQuery = mergeQuery(t.RawQuery, p.Query())
+	return t.String()
+}
+
+// indexOf returns the index of a value in a list, -1 if missing
+func indexOf(list []string, value string) int {
+	for i, v := range list {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
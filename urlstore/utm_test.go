@@ -0,0 +1,97 @@
+package urlstore
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseUTMQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    UTMParams
+		wantErr error
+	}{
+		{"empty", "", UTMParams{}, nil},
+		{"all", "utm_source=news&utm_medium=email&utm_campaign=spring+sale&utm_term=a%26b&utm_content=top",
+			UTMParams{Source: "news", Medium: "email", Campaign: "spring sale", Term: "a&b", Content: "top"}, nil},
+		{"unknown", "utm_source=news&ref=x", UTMParams{}, ErrInvalidUTM},
+		{"repeated", "utm_source=a&utm_source=b", UTMParams{}, ErrInvalidUTM},
+		{"malformed", "utm_source=%zz", UTMParams{}, ErrInvalidUTM},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseUTMQuery(tt.query)
+			require.Equal(t, tt.wantErr, err)
+			if err != nil {
+				return
+			}
+			require.Equal(t, tt.want, got)
+			// round trip
+			got, err = parseUTMQuery(got.Query())
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestUTM(t *testing.T) {
+	t.Parallel()
+	cfg := buildConifgTest()
+	cfg.Campaigns = map[string]UTMParams{
+		"newsletter": {Source: "newsletter", Medium: "email", Campaign: "weekly"},
+	}
+	s := openBackendStore(t, BackendMemory, cfg)
+	defer s.Close()
+
+	tests := []struct {
+		name    string
+		req     URLReq
+		want    string
+		wantErr error
+	}{
+		{"none", URLReq{URL: "https://ilij.li/a?x=1"}, "https://ilij.li/a?x=1", nil},
+		{"url params", URLReq{URL: "https://ilij.li/a", UTMParams: UTMParams{Source: "twitter", Campaign: "spring sale"}},
+			"https://ilij.li/a?utm_source=twitter&utm_campaign=spring+sale", nil},
+		{"target wins", URLReq{URL: "https://ilij.li/a?utm_source=partner", UTMParams: UTMParams{Source: "twitter", Medium: "social"}},
+			"https://ilij.li/a?utm_source=partner&utm_medium=social", nil},
+		{"campaign", URLReq{URL: "https://ilij.li/a", Campaign: "newsletter"},
+			"https://ilij.li/a?utm_source=newsletter&utm_medium=email&utm_campaign=weekly", nil},
+		{"campaign override", URLReq{URL: "https://ilij.li/a", Campaign: "newsletter", UTMParams: UTMParams{Campaign: "monthly", Content: "footer"}},
+			"https://ilij.li/a?utm_source=newsletter&utm_medium=email&utm_campaign=monthly&utm_content=footer", nil},
+		{"unknown campaign", URLReq{URL: "https://ilij.li/a", Campaign: "nope"}, "", ErrInvalidCampaign},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := s.UpsertURLSimple(&tt.req)
+			require.Equal(t, tt.wantErr, err)
+			if err != nil {
+				return
+			}
+			r, err := s.Redirect(id, nil)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, r.URL)
+		})
+	}
+
+	// the utm parameters are added before the forwarded query
+	on := true
+	id, err := s.UpsertURLSimple(&URLReq{URL: "https://ilij.li/a", ForwardQuery: &on, UTMParams: UTMParams{Source: "twitter"}})
+	require.NoError(t, err)
+	r, err := s.Redirect(id, &Visit{Query: "utm_source=other&q=1"})
+	require.NoError(t, err)
+	require.Equal(t, "https://ilij.li/a?utm_source=twitter&q=1", r.URL)
+
+	// patch
+	var p URLPatch
+	require.NoError(t, json.Unmarshal([]byte(`{"utm_medium": "social", "campaign": "newsletter"}`), &p))
+	u, err := s.PatchURL(id, &p, "")
+	require.NoError(t, err)
+	require.Equal(t, UTMParams{Medium: "social"}, u.UTM())
+	require.Equal(t, UTMParams{Source: "newsletter", Medium: "social", Campaign: "weekly"}, s.UTM(u))
+	nope := "nope"
+	_, err = s.PatchURL(id, &URLPatch{Campaign: &nope}, "")
+	require.Equal(t, ErrInvalidCampaign, err)
+}
@@ -0,0 +1,50 @@
+package urlstore
+
+import "time"
+
+// WindowUsage is the usage of the current window of a rate limited url
+type WindowUsage struct {
+	Requests    uint64 `json:"requests"`
+	MaxRequests uint64 `json:"max_requests"`
+	Size        uint64 `json:"size"`
+	// Reset is the end of the current window, nil if there is none
+	Reset *time.Time `json:"reset,omitempty"`
+}
+
+// RateLimited tells if the url has a max number of requests per window
+func (u URLInfo) RateLimited() bool {
+	return u.WindowMaxRequests > 0 && u.WindowSize > 0
+}
+
+// windowEnd returns the end of the current window
+func (u URLInfo) windowEnd() time.Time {
+	return u.WindowStart.Add(time.Duration(u.WindowSize) * time.Second)
+}
+
+// rollWindow starts a new window if the current one is over
+func (u *URLInfo) rollWindow(now time.Time) {
+	if !u.RateLimited() {
+		return
+	}
+	if u.WindowStart.IsZero() || !now.Before(u.windowEnd()) {
+		u.WindowStart, u.WindowCounter = now, 0
+	}
+}
+
+// WindowFull tells if the requests of the window at the time now are exhausted
+func (u URLInfo) WindowFull(now time.Time) bool {
+	return u.RateLimited() && now.Before(u.windowEnd()) && u.WindowCounter >= u.WindowMaxRequests
+}
+
+// WindowUsage returns the usage of the window at the time now,
+// nil if the url is not rate limited
+func (u URLInfo) WindowUsage(now time.Time) *WindowUsage {
+	if !u.RateLimited() {
+		return nil
+	}
+	w := &WindowUsage{MaxRequests: u.WindowMaxRequests, Size: u.WindowSize}
+	if end := u.windowEnd(); !u.WindowStart.IsZero() && now.Before(end) {
+		w.Requests, w.Reset = u.WindowCounter, &end
+	}
+	return w
+}
@@ -0,0 +1,104 @@
+package urlstore
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestURLInfo_Window(t *testing.T) {
+	t0 := time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+	u := URLInfo{WindowMaxRequests: 2, WindowSize: 60}
+	require.Equal(t, &WindowUsage{MaxRequests: 2, Size: 60}, u.WindowUsage(t0))
+
+	u.rollWindow(t0)
+	require.Equal(t, t0, u.WindowStart)
+	u.WindowCounter = 2
+	require.True(t, u.WindowFull(t0.Add(time.Second)))
+	require.Equal(t, URLStateExhausted, u.State(t0.Add(time.Second)))
+	end := t0.Add(time.Minute)
+	require.Equal(t, &WindowUsage{Requests: 2, MaxRequests: 2, Size: 60, Reset: &end}, u.WindowUsage(t0))
+
+	// the window is reset at the end
+	u.rollWindow(t0.Add(30 * time.Second))
+	require.Equal(t, uint64(2), u.WindowCounter)
+	require.False(t, u.WindowFull(end))
+	u.rollWindow(end)
+	require.Equal(t, end, u.WindowStart)
+	require.Equal(t, uint64(0), u.WindowCounter)
+
+	// no limit
+	u = URLInfo{}
+	u.rollWindow(t0)
+	require.True(t, u.WindowStart.IsZero())
+	require.Nil(t, u.WindowUsage(t0))
+}
+
+func TestRateLimit(t *testing.T) {
+	t.Parallel()
+	cfg := buildConifgTest()
+	cfg.ShortID.ExhaustedRedirectURL = "https://ilij.li/exhausted"
+	cfg.ShortID.WindowMaxRequests = 5
+	cfg.ShortID.WindowSize = 3600
+	s := openBackendStore(t, BackendMemory, cfg)
+	defer s.Close()
+
+	// the global window
+	id, err := s.UpsertURLSimple(&URLReq{URL: "https://ilij.li/a"})
+	require.NoError(t, err)
+	var wg sync.WaitGroup
+	var resM sync.Mutex
+	res := make(map[error]int)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r, err := s.Redirect(id, nil)
+			if err != nil && r.URL != "https://ilij.li/exhausted" {
+				err = ErrInvalidWindow
+			}
+			resM.Lock()
+			res[err]++
+			resM.Unlock()
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, map[error]int{nil: 5, ErrURLRateLimited: 15}, res)
+	u, _ := s.Peek(id)
+	require.Equal(t, URLStateExhausted, u.State(time.Now()))
+	require.Equal(t, uint64(5), u.WindowUsage(time.Now()).MaxRequests)
+
+	// the window state is persisted
+	require.NoError(t, s.Flush())
+	s.uc.Purge()
+	u, _ = s.Peek(id)
+	require.Equal(t, uint64(20), u.WindowCounter)
+	_, err = s.Redirect(id, nil)
+	require.Equal(t, ErrURLRateLimited, err)
+
+	// the local window has priority and the next window reopens the url
+	id, err = s.UpsertURLSimple(&URLReq{URL: "https://ilij.li/b", WindowMaxRequests: 1, WindowSize: 1, ExhaustedURL: "https://ilij.li/wait"})
+	require.NoError(t, err)
+	_, err = s.Redirect(id, nil)
+	require.NoError(t, err)
+	r, err := s.Redirect(id, nil)
+	require.Equal(t, ErrURLRateLimited, err)
+	require.Equal(t, "https://ilij.li/wait", r.URL)
+	time.Sleep(1100 * time.Millisecond)
+	_, err = s.Redirect(id, nil)
+	require.NoError(t, err)
+
+	// patch keeps the current window
+	limit := uint64(10)
+	_, err = s.PatchURL(id, &URLPatch{WindowMaxRequests: &limit}, "")
+	require.NoError(t, err)
+	u, _ = s.Peek(id)
+	require.Equal(t, uint64(1), u.WindowCounter)
+
+	// a window needs a size
+	s.Config.ShortID.WindowSize = 0
+	_, err = s.UpsertURLSimple(&URLReq{URL: "https://ilij.li/c", WindowMaxRequests: 1})
+	require.Equal(t, ErrInvalidWindow, err)
+}
@@ -3,18 +3,40 @@ package web
 
 import (
 	"crypto/subtle"
+	"fmt"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/render"
 	"github.com/jbrodriguez/mlog"
+	"github.com/noandrea/distill/pkg/common"
 	"github.com/noandrea/distill/urlstore"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 )
 
-// RegisterEndpoints register application endpoints
-func RegisterEndpoints() (router *chi.Mux) {
+const (
+	listDefaultLimit = 50
+	listMaxLimit     = 500
+	// number of values per dimension in the breakdown
+	breakdownDefaultTop = 10
+	breakdownMaxTop     = 100
+	// default range of the clicks series
+	clicksHourlyRange = 24 * time.Hour
+	clicksDailyRange  = 30 * 24 * time.Hour
+	// sticky cookie of the variant of a short url
+	variantCookieName   = "distill_variant"
+	variantCookieMaxAge = 30 * 24 * 60 * 60
+	// seconds the clients can cache a permanent redirect
+	permanentRedirectMaxAge = 24 * 60 * 60
+)
+
+// RegisterEndpoints register application endpoints for a store
+func RegisterEndpoints(store *urlstore.Store) (router *chi.Mux) {
 	router = chi.NewRouter()
 
 	// A good base middleware stack
@@ -22,6 +44,7 @@ func RegisterEndpoints() (router *chi.Mux) {
 	router.Use(middleware.RealIP)
 	router.Use(middleware.Logger)
 	router.Use(middleware.Recoverer)
+	router.Use(instrument(store.Registry()))
 
 	// Set a timeout value on the request context (ctx), that will signal
 	// through ctx.Done() that the request has timed out and further
@@ -34,26 +57,43 @@ func RegisterEndpoints() (router *chi.Mux) {
 
 	// health check route
 	router.Get("/health-check", healthCheckHandler)
+	// prometheus metrics, with their own key, disabled without a key
+	if len(store.Config.Server.MetricsKey) > 0 {
+		router.With(metricsContext(store)).Method(http.MethodGet, "/metrics", handleMetrics(store))
+	}
 	// redirect root to the configured url
 	router.Get("/", func(w http.ResponseWriter, r *http.Request) {
-		http.Redirect(w, r, urlstore.Config.ShortID.RootRedirectURL, 302)
+		redirect(w, r, store.Config.ShortID.RootRedirectURL, store.Config.ShortID.RedirectStatus)
 	})
 	// shortener redirect
-	router.Get("/{ID}", handleGetURL)
+	router.Get("/{ID}", handleGetURL(store))
+	router.Get("/{ID}/*", handleGetURL(store))
+	// password form of the protected urls
+	router.Post("/{ID}", handleGetURL(store))
+	router.Post("/{ID}/*", handleGetURL(store))
 	// handle api requests
 	router.Route("/api", func(r chi.Router) {
-		r.Use(apiContext)
+		r.Use(apiContext(store))
 		// handle global statistics
-		r.Get("/stats", handleGetStats)
-		r.Delete("/stats", handleResetStats)
+		r.Get("/stats", handleGetStats(store))
+		r.Delete("/stats", handleResetStats(store))
 		// handle url statistics
-		r.Get("/stats/{ID}", handleStatsURL)
+		r.Get("/stats/{ID}", handleStatsURL(store))
+		r.Get("/stats/{ID}/breakdown", handleBreakdownURL(store))
+		// list urls
+		r.Get("/urls", handleListURLs(store))
 		// handle url setup
-		r.Post("/short", handleShort)
+		r.Post("/short", handleShort(store))
+		r.Post("/short/batch", handleShortBatch(store))
 		// implement kutt.it endpoint
-		r.Post("/url/submit", handleShort)
-		// delete an id
-		r.Delete("/short/{ID}", handleDeleteURL)
+		r.Post("/url/submit", handleShort(store))
+		// partial update of an id
+		r.Patch("/short/{ID}", handlePatchURL(store))
+		// delete an id, moving it to the trash
+		r.Delete("/short/{ID}", handleDeleteURL(store))
+		// trash
+		r.Get("/trash", handleListTrash(store))
+		r.Post("/trash/{ID}/restore", handleRestoreURL(store))
 		// backup
 	})
 	return router
@@ -67,79 +107,399 @@ func RegisterEndpoints() (router *chi.Mux) {
 //  |____||____||____| |____||_____|\____||______.'|________||________||____| |___|\______.'
 //
 
-func handleShort(w http.ResponseWriter, r *http.Request) {
-	urlReq := &urlstore.URLReq{}
-	if err := render.Bind(r, urlReq); err != nil {
-		render.Render(w, r, ErrInvalidRequest(err, err.Error()))
-		return
+func handleShort(store *urlstore.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		urlReq := &urlstore.URLReq{}
+		if err := render.Bind(r, urlReq); err != nil {
+			render.Render(w, r, ErrInvalidRequest(err, err.Error()))
+			return
+		}
+		// retrieve the forceAlphabet and forceLength
+		forceAlphabet, forceLenght := false, false
+		fA := chi.URLParam(r, "forceAlphabet")
+		fL := chi.URLParam(r, "forceLenght")
+		if fA == "1" {
+			forceAlphabet = true
+		}
+		if fL == "1" {
+			forceLenght = true
+		}
+		// upsert the data
+		id, err := store.UpsertURL(urlReq, forceAlphabet, forceLenght, time.Now())
+		mlog.Trace("created %v", id)
+		// TODO: check the actual error
+		if err != nil {
+			render.Render(w, r, ErrInvalidRequest(err, err.Error()))
+			return
+		}
+		render.JSON(w, r, urlstore.ShortID{ID: id})
 	}
-	// retrieve the forceAlphabet and forceLength
-	forceAlphabet, forceLenght := false, false
-	fA := chi.URLParam(r, "forceAlphabet")
-	fL := chi.URLParam(r, "forceLenght")
-	if fA == "1" {
-		forceAlphabet = true
+}
+
+func handleShortBatch(store *urlstore.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		count, err := strconv.Atoi(r.URL.Query().Get("count"))
+		if err != nil {
+			render.Render(w, r, ErrInvalidRequest(err, "invalid count"))
+			return
+		}
+		urlReq := &urlstore.URLReq{}
+		if err := render.Bind(r, urlReq); err != nil {
+			render.Render(w, r, ErrInvalidRequest(err, err.Error()))
+			return
+		}
+		ids, err := store.UpsertURLs(urlReq, count, time.Now())
+		if err != nil {
+			render.Render(w, r, ErrInvalidRequest(err, err.Error()))
+			return
+		}
+		render.JSON(w, r, urlstore.ShortIDs{IDs: ids})
 	}
-	if fL == "1" {
-		forceLenght = true
+}
+
+func handleGetURL(store *urlstore.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		shortID := chi.URLParam(r, "ID")
+		visit := &urlstore.Visit{
+			IP:             clientIP(r),
+			Referrer:       r.Referer(),
+			UserAgent:      r.UserAgent(),
+			AcceptLanguage: r.Header.Get("Accept-Language"),
+			Path:           trailingPath(r),
+			Query:          r.URL.RawQuery,
+			Password:       formPassword(w, r),
+		}
+		if c, err := r.Cookie(variantCookieName); err == nil {
+			visit.Variant = c.Value
+		}
+		target, err := store.Redirect(shortID, visit)
+		if renderPasswordForm(w, err) {
+			return
+		}
+		if err != nil && len(target.URL) == 0 {
+			http.Error(w, "URL not found", 404)
+			return
+		}
+		// keep the client on the same variant
+		if len(target.Variant) > 0 {
+			http.SetCookie(w, &http.Cookie{
+				Name:     variantCookieName,
+				Value:    target.Variant,
+				Path:     "/" + shortID,
+				MaxAge:   variantCookieMaxAge,
+				HttpOnly: true,
+			})
+		}
+		// the redirect after the password form is always a GET
+		if r.Method == http.MethodPost {
+			target.Status = http.StatusSeeOther
+		}
+		// send redirect
+		redirect(w, r, target.URL, target.Status)
 	}
-	// upsert the data
-	id, err := urlstore.UpsertURL(urlReq, forceAlphabet, forceLenght, time.Now())
-	mlog.Trace("created %v", id)
-	// TODO: check the actual error
-	if err != nil {
-		render.Render(w, r, ErrInvalidRequest(err, err.Error()))
-		return
+}
+
+// trailingPath returns the escaped path after the short id
+func trailingPath(r *http.Request) string {
+	p := strings.TrimPrefix(r.URL.EscapedPath(), "/")
+	if i := strings.Index(p, "/"); i >= 0 {
+		return p[i+1:]
 	}
-	render.JSON(w, r, urlstore.ShortID{ID: id})
+	return ""
 }
 
-func handleGetURL(w http.ResponseWriter, r *http.Request) {
-	shortID := chi.URLParam(r, "ID")
-	targetURL, err := urlstore.GetURLRedirect(shortID)
-	if err != nil && len(targetURL) == 0 {
-		http.Error(w, "URL not found", 404)
+// redirect sends a redirect with the cache headers matching the status,
+// the permanent redirects are cached by the client (only, since the
+// target can depend on the client) while the temporary ones are not
+// cached so that every click is counted
+func redirect(w http.ResponseWriter, r *http.Request, target string, status int) {
+	if !urlstore.ValidRedirectStatus(status) {
+		status = http.StatusFound
 	}
-	// send redirect
-	http.Redirect(w, r, targetURL, 302)
-	return
+	if urlstore.PermanentRedirectStatus(status) {
+		w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", permanentRedirectMaxAge))
+	} else {
+		w.Header().Set("Cache-Control", "private, no-cache, no-store, must-revalidate")
+	}
+	http.Redirect(w, r, target, status)
 }
 
 func healthCheckHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("good"))
 }
 
-func handleStatsURL(w http.ResponseWriter, r *http.Request) {
-	shortID := chi.URLParam(r, "ID")
-	if urlInfo, err := urlstore.GetURLInfo(shortID); err == nil {
-		// send redirect
+func handleStatsURL(store *urlstore.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		shortID := chi.URLParam(r, "ID")
+		urlInfo, err := store.GetURLInfo(shortID)
+		if err != nil {
+			http.Error(w, "URL not found", 404)
+			return
+		}
+		stats := urlstore.URLStats{URLInfo: urlInfo}
+		if stats.Uniques, err = store.Uniques(shortID); err != nil {
+			render.Render(w, r, ErrInternalError(err, err.Error()))
+			return
+		}
+		// the clicks series is included when requested
+		q := r.URL.Query()
+		if len(q.Get("granularity")) > 0 || len(q.Get("from")) > 0 || len(q.Get("to")) > 0 {
+			granularity := q.Get("granularity")
+			common.DefaultIfEmptyStr(&granularity, urlstore.GranularityDay)
+			from, to, err := queryRange(q, granularity)
+			if err != nil {
+				render.Render(w, r, ErrInvalidRequest(err, err.Error()))
+				return
+			}
+			if stats.Clicks, err = store.ClickSeries(shortID, granularity, from, to); err != nil {
+				render.Render(w, r, ErrInvalidRequest(err, err.Error()))
+				return
+			}
+		}
+		if stats.Geo, err = store.Geo(shortID, breakdownDefaultTop); err != nil {
+			render.Render(w, r, ErrInternalError(err, err.Error()))
+			return
+		}
+		if stats.VariantClicks, err = store.VariantClicks(shortID); err != nil {
+			render.Render(w, r, ErrInternalError(err, err.Error()))
+			return
+		}
+		stats.Protected = urlInfo.Protected()
+		if idle := urlInfo.IdleExpireOn(); !idle.IsZero() {
+			stats.IdleExpireOn = &idle
+		}
+		stats.Window = urlInfo.WindowUsage(time.Now())
+		if utm := store.UTM(urlInfo); !utm.IsEmpty() {
+			stats.UTM = &utm
+		}
+		w.Header().Set("ETag", quoteETag(urlInfo.ETag()))
+		render.JSON(w, r, stats)
+	}
+}
+
+func handleBreakdownURL(store *urlstore.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		shortID := chi.URLParam(r, "ID")
+		top := breakdownDefaultTop
+		if v := r.URL.Query().Get("top"); len(v) > 0 {
+			var err error
+			if top, err = strconv.Atoi(v); err != nil || top <= 0 || top > breakdownMaxTop {
+				err = fmt.Errorf("top must be between 1 and %d", breakdownMaxTop)
+				render.Render(w, r, ErrInvalidRequest(err, err.Error()))
+				return
+			}
+		}
+		b, err := store.Breakdown(shortID, top)
+		switch err {
+		case nil:
+		case urlstore.ErrKeyNotFound:
+			render.Render(w, r, ErrNotFound(err, "URL id not found"))
+			return
+		default:
+			render.Render(w, r, ErrInternalError(err, err.Error()))
+			return
+		}
+		render.JSON(w, r, b)
+	}
+}
+
+func handlePatchURL(store *urlstore.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		shortID := chi.URLParam(r, "ID")
+		patch := &urlstore.URLPatch{}
+		if err := render.Bind(r, patch); err != nil {
+			render.Render(w, r, ErrInvalidRequest(err, err.Error()))
+			return
+		}
+		urlInfo, err := store.PatchURL(shortID, patch, r.Header.Get("If-Match"))
+		switch err {
+		case nil:
+		case urlstore.ErrKeyNotFound:
+			render.Render(w, r, ErrNotFound(err, "URL id not found"))
+			return
+		case urlstore.ErrETagMismatch:
+			render.Render(w, r, ErrPreconditionFailed(err, err.Error()))
+			return
+		default:
+			render.Render(w, r, ErrInvalidRequest(err, err.Error()))
+			return
+		}
+		w.Header().Set("ETag", quoteETag(urlInfo.ETag()))
 		render.JSON(w, r, urlInfo)
-		return
 	}
-	http.Error(w, "URL not found", 404)
 }
 
-func handleGetStats(w http.ResponseWriter, r *http.Request) {
-	render.JSON(w, r, *urlstore.GetStats())
+func handleListURLs(store *urlstore.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		f := &urlstore.URLFilter{
+			URL:   q.Get("url"),
+			State: q.Get("state"),
+		}
+		switch f.State {
+		case "", urlstore.URLStateActive, urlstore.URLStateExpired, urlstore.URLStateExhausted, urlstore.URLStateInactive:
+		default:
+			err := fmt.Errorf("invalid state %v", f.State)
+			render.Render(w, r, ErrInvalidRequest(err, err.Error()))
+			return
+		}
+		var err error
+		if f.CreatedFrom, err = queryTime(q, "created_from"); err != nil {
+			render.Render(w, r, ErrInvalidRequest(err, err.Error()))
+			return
+		}
+		if f.CreatedTo, err = queryTime(q, "created_to"); err != nil {
+			render.Render(w, r, ErrInvalidRequest(err, err.Error()))
+			return
+		}
+		limit, err := queryLimit(q)
+		if err != nil {
+			render.Render(w, r, ErrInvalidRequest(err, err.Error()))
+			return
+		}
+		l, err := store.ListURLs(f, q.Get("cursor"), limit)
+		if err != nil {
+			render.Render(w, r, ErrInternalError(err, err.Error()))
+			return
+		}
+		render.JSON(w, r, l)
+	}
+}
+
+func handleGetStats(store *urlstore.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		renderStats(w, r, store)
+	}
+}
+
+func handleResetStats(store *urlstore.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := store.ResetStats()
+		if err != nil {
+			render.Render(w, r, ErrInternalError(err, err.Error()))
+			return
+		}
+		renderStats(w, r, store)
+	}
 }
 
-func handleResetStats(w http.ResponseWriter, r *http.Request) {
-	err := urlstore.ResetStats()
+// renderStats renders the global statistics with the unique visitors
+func renderStats(w http.ResponseWriter, r *http.Request, store *urlstore.Store) {
+	stats := *store.GetStats()
+	uniques, err := store.GlobalUniques()
 	if err != nil {
 		render.Render(w, r, ErrInternalError(err, err.Error()))
 		return
 	}
-	render.JSON(w, r, urlstore.GetStats())
+	stats.Uniques = uniques
+	render.JSON(w, r, stats)
 }
 
-func handleDeleteURL(w http.ResponseWriter, r *http.Request) {
-	shortID := chi.URLParam(r, "ID")
-	err := urlstore.DeleteURL(shortID)
-	if err != nil {
-		render.Render(w, r, ErrNotFound(err, "URL id not found"))
+func handleDeleteURL(store *urlstore.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		shortID := chi.URLParam(r, "ID")
+		err := store.TrashURL(shortID)
+		if err != nil {
+			render.Render(w, r, ErrNotFound(err, "URL id not found"))
+			return
+		}
+		render.JSON(w, r, urlstore.ShortID{ID: shortID})
+	}
+}
+
+func handleListTrash(store *urlstore.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		limit, err := queryLimit(q)
+		if err != nil {
+			render.Render(w, r, ErrInvalidRequest(err, err.Error()))
+			return
+		}
+		l, err := store.ListTrash(q.Get("cursor"), limit)
+		if err != nil {
+			render.Render(w, r, ErrInternalError(err, err.Error()))
+			return
+		}
+		render.JSON(w, r, l)
+	}
+}
+
+func handleRestoreURL(store *urlstore.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		shortID := chi.URLParam(r, "ID")
+		_, err := store.RestoreURL(shortID)
+		switch err {
+		case nil:
+		case urlstore.ErrURLExists:
+			render.Render(w, r, ErrConflict(err, err.Error()))
+			return
+		default:
+			render.Render(w, r, ErrNotFound(err, "URL id not found in the trash"))
+			return
+		}
+		render.JSON(w, r, urlstore.ShortID{ID: shortID})
+	}
+}
+
+// clientIP returns the ip of the client, after the RealIP
+// middleware the remote address can be with or without port
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// quoteETag format an etag for the ETag header
+func quoteETag(etag string) string {
+	return `"` + etag + `"`
+}
+
+// queryLimit parse the optional page size of a list from the query string
+func queryLimit(q url.Values) (limit int, err error) {
+	limit = listDefaultLimit
+	if v := q.Get("limit"); len(v) > 0 {
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 || limit > listMaxLimit {
+			err = fmt.Errorf("limit must be between 1 and %d", listMaxLimit)
+		}
+	}
+	return
+}
+
+// queryTime parse an optional RFC3339 time from the query string
+func queryTime(q url.Values, name string) (t time.Time, err error) {
+	v := q.Get(name)
+	if len(v) == 0 {
+		return
+	}
+	if t, err = time.Parse(time.RFC3339, v); err != nil {
+		err = fmt.Errorf("%s must be a RFC3339 date", name)
+	}
+	return
+}
+
+// queryRange parse the from/to range of a clicks series,
+// by default the range ends now and spans a day or a month
+func queryRange(q url.Values, granularity string) (from, to time.Time, err error) {
+	if from, err = queryTime(q, "from"); err != nil {
 		return
 	}
-	render.JSON(w, r, urlstore.ShortID{ID: shortID})
+	if to, err = queryTime(q, "to"); err != nil {
+		return
+	}
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.IsZero() {
+		from = to.Add(-clicksDailyRange)
+		if granularity == urlstore.GranularityHour {
+			from = to.Add(-clicksHourlyRange)
+		}
+	}
+	if !from.Before(to) {
+		err = fmt.Errorf("from must be before to")
+	}
+	return
 }
 
 //   ____    ____   ______     ______    ______
@@ -180,6 +540,26 @@ func ErrNotFound(err error, message string) render.Renderer {
 	}
 }
 
+// ErrConflict render a conflict with the current state
+func ErrConflict(err error, message string) render.Renderer {
+	return &ErrResponse{
+		Err:            err,
+		HTTPStatusCode: http.StatusConflict,
+		AppCode:        http.StatusConflict,
+		ErrorText:      message,
+	}
+}
+
+// ErrPreconditionFailed render a failed precondition
+func ErrPreconditionFailed(err error, message string) render.Renderer {
+	return &ErrResponse{
+		Err:            err,
+		HTTPStatusCode: http.StatusPreconditionFailed,
+		AppCode:        http.StatusPreconditionFailed,
+		ErrorText:      message,
+	}
+}
+
 // Render an ErrResponse
 func (e *ErrResponse) Render(w http.ResponseWriter, r *http.Request) error {
 	render.Status(r, e.HTTPStatusCode)
@@ -208,16 +588,18 @@ type ErrResponse struct {
 //
 
 // apiContext verify the api key header
-func apiContext(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		apiKey := r.Header.Get(urlstore.Config.Tuning.APIKeyHeaderName)
-		keyMatch := subtle.ConstantTimeCompare([]byte(apiKey), []byte(urlstore.Config.Server.APIKey))
-		if keyMatch == 0 {
-			http.Error(w, http.StatusText(403), 403)
-			return
-		}
-		next.ServeHTTP(w, r)
-	})
+func apiContext(store *urlstore.Store) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			apiKey := r.Header.Get(store.Config.Tuning.APIKeyHeaderName)
+			keyMatch := subtle.ConstantTimeCompare([]byte(apiKey), []byte(store.Config.Server.APIKey))
+			if keyMatch == 0 {
+				http.Error(w, http.StatusText(403), 403)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
 }
 
 // cors handler for cors headers
@@ -1,11 +1,26 @@
 package web
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/jbrodriguez/mlog"
+	"github.com/noandrea/distill/urlstore"
 )
 
+func TestMain(m *testing.M) {
+	mlog.Start(mlog.LevelInfo, "")
+	os.Exit(m.Run())
+}
+
 func TestRegisterEndpoints(t *testing.T) {
 
 	tests := []struct {
@@ -45,3 +60,558 @@ func TestRegisterEndpoints(t *testing.T) {
 		})
 	}
 }
+
+// newTestStore creates an in memory store for tests
+func newTestStore(t *testing.T) *urlstore.Store {
+	cfg := urlstore.ConfigSchema{}
+	cfg.Server.APIKey = "test"
+	cfg.Server.DbBackend = urlstore.BackendMemory
+	cfg.Defaults()
+	s, err := urlstore.NewStore(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestListURLs(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+	for i := 0; i < 7; i++ {
+		store.UpsertURLSimple(&urlstore.URLReq{URL: fmt.Sprintf("https://ex.com/%d", i)})
+	}
+	router := RegisterEndpoints(store)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantURLs   int
+	}{
+		{"all", "", http.StatusOK, 7},
+		{"limit", "?limit=3", http.StatusOK, 3},
+		{"filter", "?url=ex.com/4", http.StatusOK, 1},
+		{"invalid limit", "?limit=0", http.StatusBadRequest, 0},
+		{"invalid state", "?state=gone", http.StatusBadRequest, 0},
+		{"invalid date", "?created_from=yesterday", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/urls"+tt.query, nil)
+			req.Header.Set(store.Config.Tuning.APIKeyHeaderName, store.Config.Server.APIKey)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tt.wantStatus)
+			}
+			if rr.Code != http.StatusOK {
+				return
+			}
+			l := urlstore.URLList{}
+			if err := json.NewDecoder(rr.Body).Decode(&l); err != nil {
+				t.Fatal(err)
+			}
+			if len(l.URLs) != tt.wantURLs {
+				t.Errorf("got %v urls want %v", len(l.URLs), tt.wantURLs)
+			}
+		})
+	}
+}
+
+func TestPatchURL(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+	id, _ := store.UpsertURLSimple(&urlstore.URLReq{URL: "https://ex.com/a"})
+	router := RegisterEndpoints(store)
+
+	do := func(method, path, body, ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(store.Config.Tuning.APIKeyHeaderName, store.Config.Server.APIKey)
+		if len(ifMatch) > 0 {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := do("GET", "/api/stats/"+id, "", "")
+	etag := rr.Header().Get("ETag")
+	if rr.Code != http.StatusOK || len(etag) == 0 {
+		t.Fatalf("missing etag, status %v", rr.Code)
+	}
+	rr = do("PATCH", "/api/short/"+id, `{"url": "https://ex.com/b"}`, etag)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("patch returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	if rr.Header().Get("ETag") == etag {
+		t.Errorf("etag not updated")
+	}
+	rr = do("PATCH", "/api/short/"+id, `{"url": "https://ex.com/c"}`, etag)
+	if rr.Code != http.StatusPreconditionFailed {
+		t.Errorf("stale patch returned wrong status code: got %v want %v", rr.Code, http.StatusPreconditionFailed)
+	}
+	// a list of etags matches any of them, the weak ones never match
+	current := do("GET", "/api/stats/"+id, "", "").Header().Get("ETag")
+	rr = do("PATCH", "/api/short/"+id, `{"url": "https://ex.com/c"}`, "W/"+current)
+	if rr.Code != http.StatusPreconditionFailed {
+		t.Errorf("weak patch returned wrong status code: got %v want %v", rr.Code, http.StatusPreconditionFailed)
+	}
+	rr = do("PATCH", "/api/short/"+id, `{"url": "https://ex.com/b"}`, etag+", "+current)
+	if rr.Code != http.StatusOK {
+		t.Errorf("patch returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	rr = do("PATCH", "/api/short/notfound", `{"url": "https://ex.com/c"}`, "")
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("patch returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+	}
+	if u, _ := store.GetURLInfo(id); u.URL != "https://ex.com/b" {
+		t.Errorf("got url %v want %v", u.URL, "https://ex.com/b")
+	}
+}
+
+func TestStatsURL(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+	id, _ := store.UpsertURLSimple(&urlstore.URLReq{URL: "https://ex.com/a"})
+	router := RegisterEndpoints(store)
+	for i := 0; i < 3; i++ {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/"+id, nil))
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantClicks uint64
+		wantSeries bool
+	}{
+		{"info", "", http.StatusOK, 0, false},
+		{"hourly", "?granularity=hour", http.StatusOK, 3, true},
+		{"daily", "?from=2019-12-01T00:00:00Z", http.StatusOK, 3, true},
+		{"past", "?from=2019-12-01T00:00:00Z&to=2019-12-02T00:00:00Z", http.StatusOK, 0, true},
+		{"invalid granularity", "?granularity=week", http.StatusBadRequest, 0, false},
+		{"invalid range", "?from=2019-12-02T00:00:00Z&to=2019-12-01T00:00:00Z", http.StatusBadRequest, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/stats/"+id+tt.query, nil)
+			req.Header.Set(store.Config.Tuning.APIKeyHeaderName, store.Config.Server.APIKey)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tt.wantStatus)
+			}
+			if rr.Code != http.StatusOK {
+				return
+			}
+			stats := urlstore.URLStats{}
+			if err := json.NewDecoder(rr.Body).Decode(&stats); err != nil {
+				t.Fatal(err)
+			}
+			if stats.URLInfo == nil || stats.ID != id || stats.Counter != 3 {
+				t.Fatalf("wrong url info %v", stats.URLInfo)
+			}
+			// the requests come from the same client
+			if stats.Uniques != 1 {
+				t.Errorf("got %v uniques want %v", stats.Uniques, 1)
+			}
+			if (stats.Clicks != nil) != tt.wantSeries {
+				t.Fatalf("got series %v want %v", stats.Clicks != nil, tt.wantSeries)
+			}
+			if stats.Clicks != nil && stats.Clicks.Total != tt.wantClicks {
+				t.Errorf("got %v clicks want %v", stats.Clicks.Total, tt.wantClicks)
+			}
+		})
+	}
+}
+
+func TestBreakdownURL(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+	id, _ := store.UpsertURLSimple(&urlstore.URLReq{URL: "https://ex.com/a"})
+	router := RegisterEndpoints(store)
+	for _, ref := range []string{"https://news.ycombinator.com/", "https://t.co/x", "https://t.co/y"} {
+		req := httptest.NewRequest("GET", "/"+id, nil)
+		req.Header.Set("Referer", ref)
+		req.Header.Set("Accept-Language", "en-GB,en;q=0.9")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+	}{
+		{"breakdown", "/api/stats/" + id + "/breakdown?top=1", http.StatusOK},
+		{"invalid top", "/api/stats/" + id + "/breakdown?top=0", http.StatusBadRequest},
+		{"not found", "/api/stats/notfound/breakdown", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			req.Header.Set(store.Config.Tuning.APIKeyHeaderName, store.Config.Server.APIKey)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tt.wantStatus)
+			}
+			if rr.Code != http.StatusOK {
+				return
+			}
+			b := urlstore.Breakdown{}
+			if err := json.NewDecoder(rr.Body).Decode(&b); err != nil {
+				t.Fatal(err)
+			}
+			want := []urlstore.BreakdownEntry{{Value: "t.co", Clicks: 2}}
+			if got := b[urlstore.BreakdownReferrer]; !reflect.DeepEqual(got, want) {
+				t.Errorf("got referrers %v want %v", got, want)
+			}
+			want = []urlstore.BreakdownEntry{{Value: "en", Clicks: 3}}
+			if got := b[urlstore.BreakdownLanguage]; !reflect.DeepEqual(got, want) {
+				t.Errorf("got languages %v want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestGetURLVariant(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+	id, _ := store.UpsertURLSimple(&urlstore.URLReq{URL: "https://ex.com", Variants: []*urlstore.Variant{
+		{URL: "https://ex.com/a", Weight: 1},
+		{URL: "https://ex.com/b", Weight: 1},
+	}})
+	router := RegisterEndpoints(store)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/"+id, nil))
+	cookies := rr.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != variantCookieName || cookies[0].Path != "/"+id {
+		t.Fatalf("wrong variant cookie %v", cookies)
+	}
+	target := rr.Header().Get("Location")
+	// the returning client gets the same variant
+	for i := 0; i < 10; i++ {
+		req := httptest.NewRequest("GET", "/"+id, nil)
+		req.AddCookie(cookies[0])
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if got := rr.Header().Get("Location"); got != target {
+			t.Fatalf("got variant %v want %v", got, target)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/api/stats/"+id, nil)
+	req.Header.Set(store.Config.Tuning.APIKeyHeaderName, store.Config.Server.APIKey)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	stats := urlstore.URLStats{}
+	if err := json.NewDecoder(rr.Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+	clicks := map[string]uint64{}
+	for _, v := range stats.VariantClicks {
+		clicks[v.URL] = v.Clicks
+	}
+	if len(stats.VariantClicks) != 2 || clicks[target] != 11 {
+		t.Errorf("wrong variant clicks %v", stats.VariantClicks)
+	}
+}
+
+func TestRedirectStatus(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+	store.Config.ShortID.RedirectStatus = http.StatusSeeOther
+	permanent, _ := store.UpsertURLSimple(&urlstore.URLReq{URL: "https://ex.com/a", RedirectStatus: http.StatusPermanentRedirect})
+	temporary, _ := store.UpsertURLSimple(&urlstore.URLReq{URL: "https://ex.com/b"})
+	router := RegisterEndpoints(store)
+
+	tests := []struct {
+		name             string
+		path             string
+		wantStatus       int
+		wantCacheControl string
+	}{
+		{"root", "/", http.StatusSeeOther, "private, no-cache, no-store, must-revalidate"},
+		{"permanent", "/" + permanent, http.StatusPermanentRedirect, "private, max-age=86400"},
+		{"global", "/" + temporary, http.StatusSeeOther, "private, no-cache, no-store, must-revalidate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest("GET", tt.path, nil))
+			if rr.Code != tt.wantStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.wantStatus)
+			}
+			if got := rr.Header().Get("Cache-Control"); got != tt.wantCacheControl {
+				t.Errorf("got Cache-Control %v want %v", got, tt.wantCacheControl)
+			}
+		})
+	}
+}
+
+func TestGetURLPassthrough(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+	store.Config.ShortID.ForwardQuery = true
+	on := true
+	forward, _ := store.UpsertURLSimple(&urlstore.URLReq{URL: "https://ex.com/a?ref=x", ForwardPath: &on})
+	plain, _ := store.UpsertURLSimple(&urlstore.URLReq{URL: "https://ex.com/b"})
+	router := RegisterEndpoints(store)
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"path and query", "/" + forward + "/docs/a%20b?utm_source=mail&ref=y", "https://ex.com/a/docs/a%20b?ref=x&utm_source=mail"},
+		{"trailing slash", "/" + forward + "/", "https://ex.com/a?ref=x"},
+		{"query only", "/" + plain + "/docs?utm_source=mail", "https://ex.com/b?utm_source=mail"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest("GET", tt.path, nil))
+			if rr.Code != http.StatusFound {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusFound)
+			}
+			if got := rr.Header().Get("Location"); got != tt.want {
+				t.Errorf("got Location %v want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShortUTM(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+	store.Config.Campaigns = map[string]urlstore.UTMParams{"newsletter": {Source: "newsletter", Medium: "email"}}
+	router := RegisterEndpoints(store)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(store.Config.Tuning.APIKeyHeaderName, store.Config.Server.APIKey)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := do("POST", "/api/short", `{"url": "https://ex.com/a?utm_medium=banner", "campaign": "newsletter", "utm_content": "top"}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	sid := urlstore.ShortID{}
+	if err := json.NewDecoder(rr.Body).Decode(&sid); err != nil {
+		t.Fatal(err)
+	}
+	rr = do("GET", "/"+sid.ID, "")
+	if want := "https://ex.com/a?utm_medium=banner&utm_source=newsletter&utm_content=top"; rr.Header().Get("Location") != want {
+		t.Errorf("got Location %v want %v", rr.Header().Get("Location"), want)
+	}
+	rr = do("GET", "/api/stats/"+sid.ID, "")
+	stats := urlstore.URLStats{}
+	if err := json.NewDecoder(rr.Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+	if want := (urlstore.UTMParams{Source: "newsletter", Medium: "email", Content: "top"}); stats.UTM == nil || *stats.UTM != want {
+		t.Errorf("got utm %v want %v", stats.UTM, want)
+	}
+	rr = do("POST", "/api/short", `{"url": "https://ex.com/a", "campaign": "unknown"}`)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+}
+
+func TestGetURLPassword(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+	store.Config.ShortID.PasswordMaxAttempts = 1
+	id, _ := store.UpsertURLSimple(&urlstore.URLReq{URL: "https://ex.com/a", Password: "s3cret"})
+	router := RegisterEndpoints(store)
+
+	post := func(password, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/"+id+"?q=1", strings.NewReader(url.Values{"password": {password}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.RemoteAddr = ip + ":1234"
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/"+id, nil))
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `type="password"`) {
+		t.Errorf("expected the password form, got %v %v", rr.Code, rr.Body.String())
+	}
+	if rr = post("wrong", "1.1.1.1"); rr.Code != http.StatusUnauthorized {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusUnauthorized)
+	}
+	if rr = post("s3cret", "1.1.1.1"); rr.Code != http.StatusTooManyRequests {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusTooManyRequests)
+	}
+	rr = post("s3cret", "2.2.2.2")
+	if rr.Code != http.StatusSeeOther || rr.Header().Get("Location") != "https://ex.com/a" {
+		t.Errorf("got %v %v want %v %v", rr.Code, rr.Header().Get("Location"), http.StatusSeeOther, "https://ex.com/a")
+	}
+
+	req := httptest.NewRequest("GET", "/api/stats/"+id, nil)
+	req.Header.Set(store.Config.Tuning.APIKeyHeaderName, store.Config.Server.APIKey)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	u, _ := store.GetURLInfo(id)
+	if strings.Contains(rr.Body.String(), u.PasswordHash) || !strings.Contains(rr.Body.String(), `"protected":true`) {
+		t.Errorf("unexpected stats %v", rr.Body.String())
+	}
+	if u.Counter != 1 {
+		t.Errorf("got counter %v want 1", u.Counter)
+	}
+}
+
+func TestShortBatch(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+	store.Config.ShortID.ExhaustedRedirectURL = "https://ex.com/used"
+	router := RegisterEndpoints(store)
+
+	do := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(store.Config.Tuning.APIKeyHeaderName, store.Config.Server.APIKey)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := do("/api/short/batch?count=3", `{"url": "https://ex.com/invite", "one_time": true}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	ids := urlstore.ShortIDs{}
+	if err := json.NewDecoder(rr.Body).Decode(&ids); err != nil {
+		t.Fatal(err)
+	}
+	if len(ids.IDs) != 3 {
+		t.Fatalf("got %v ids want 3", len(ids.IDs))
+	}
+	for _, id := range ids.IDs {
+		for i, want := range []string{"https://ex.com/invite", store.Config.ShortID.ExhaustedRedirectURL} {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest("GET", "/"+id, nil))
+			if got := rr.Header().Get("Location"); got != want {
+				t.Errorf("request %d got Location %v want %v", i, got, want)
+			}
+		}
+	}
+	for _, path := range []string{"/api/short/batch", "/api/short/batch?count=0", "/api/short/batch?count=1001"} {
+		if rr := do(path, `{"url": "https://ex.com/invite"}`); rr.Code != http.StatusBadRequest {
+			t.Errorf("%v returned wrong status code: got %v want %v", path, rr.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestGetURLRateLimited(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+	router := RegisterEndpoints(store)
+	id, _ := store.UpsertURLSimple(&urlstore.URLReq{URL: "https://ex.com/a", WindowMaxRequests: 1, WindowSize: 3600, ExhaustedURL: "https://ex.com/full"})
+
+	do := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set(store.Config.Tuning.APIKeyHeaderName, store.Config.Server.APIKey)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	for _, want := range []string{"https://ex.com/a", "https://ex.com/full"} {
+		if rr := do("/" + id); rr.Header().Get("Location") != want {
+			t.Errorf("got Location %v want %v", rr.Header().Get("Location"), want)
+		}
+	}
+	stats := urlstore.URLStats{}
+	if err := json.NewDecoder(do("/api/stats/" + id).Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Window == nil || stats.Window.Requests != 2 || stats.Window.MaxRequests != 1 || stats.Window.Reset == nil {
+		t.Errorf("got window %v", stats.Window)
+	}
+}
+
+func TestGetURLInactive(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+	router := RegisterEndpoints(store)
+	activeFrom := time.Now().Add(time.Hour)
+	id, _ := store.UpsertURLSimple(&urlstore.URLReq{URL: "https://ex.com/a", ActiveFrom: activeFrom})
+	own, _ := store.UpsertURLSimple(&urlstore.URLReq{URL: "https://ex.com/a", ActiveFrom: activeFrom, InactiveURL: "https://ex.com/soon"})
+
+	tests := []struct {
+		name string
+		id   string
+		want string
+	}{
+		{"default inactive url", id, store.Config.ShortID.InactiveRedirectURL},
+		{"url inactive url", own, "https://ex.com/soon"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest("GET", "/"+tt.id, nil))
+			if rr.Code != http.StatusFound {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusFound)
+			}
+			if got := rr.Header().Get("Location"); len(tt.want) == 0 || got != tt.want {
+				t.Errorf("got Location %v want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrash(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+	router := RegisterEndpoints(store)
+	id, _ := store.UpsertURLSimple(&urlstore.URLReq{URL: "https://ex.com/qr"})
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set(store.Config.Tuning.APIKeyHeaderName, store.Config.Server.APIKey)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{"delete", "DELETE", "/api/short/" + id, http.StatusOK},
+		{"deleted", "GET", "/" + id, http.StatusNotFound},
+		{"delete again", "DELETE", "/api/short/" + id, http.StatusNotFound},
+		{"trash", "GET", "/api/trash", http.StatusOK},
+		{"invalid limit", "GET", "/api/trash?limit=0", http.StatusBadRequest},
+		{"restore", "POST", "/api/trash/" + id + "/restore", http.StatusOK},
+		{"restored", "GET", "/" + id, http.StatusFound},
+		{"restore again", "POST", "/api/trash/" + id + "/restore", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		rr := do(tt.method, tt.path)
+		if rr.Code != tt.wantStatus {
+			t.Fatalf("%s returned wrong status code: got %v want %v", tt.name, rr.Code, tt.wantStatus)
+		}
+		if tt.name != "trash" {
+			continue
+		}
+		l := urlstore.URLList{}
+		if err := json.NewDecoder(rr.Body).Decode(&l); err != nil {
+			t.Fatal(err)
+		}
+		if len(l.URLs) != 1 || l.URLs[0].ID != id {
+			t.Errorf("got trash %v want %v", l.URLs, id)
+		}
+	}
+}
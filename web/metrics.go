@@ -0,0 +1,81 @@
+package web
+
+import (
+	"crypto/subtle"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/noandrea/distill/urlstore"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// routeNotFound is the route label for the requests that do not match any route
+const routeNotFound = "not_found"
+
+// handleMetrics serves the store metrics in the prometheus format
+func handleMetrics(store *urlstore.Store) http.Handler {
+	return promhttp.HandlerFor(store.Registry(), promhttp.HandlerOpts{})
+}
+
+// instrument records the latency of the requests per route in reg,
+// together with the go runtime and process metrics
+func instrument(reg prometheus.Registerer) func(http.Handler) http.Handler {
+	latency := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "distill",
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of the http requests per route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "code"})
+	for _, c := range []prometheus.Collector{
+		latency,
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+	} {
+		if err := reg.Register(c); err != nil {
+			// the endpoints are registered more than once for the same store
+			are, ok := err.(prometheus.AlreadyRegisteredError)
+			if !ok {
+				panic(err)
+			}
+			if c == latency {
+				latency = are.ExistingCollector.(*prometheus.HistogramVec)
+			}
+		}
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+			route := routeNotFound
+			if rc := chi.RouteContext(r.Context()); rc != nil && len(rc.RoutePatterns) > 0 {
+				route = rc.RoutePattern()
+			}
+			code := ww.Status()
+			if code == 0 {
+				code = http.StatusOK
+			}
+			latency.WithLabelValues(route, r.Method, strconv.Itoa(code)).Observe(time.Since(start).Seconds())
+		})
+	}
+}
+
+// metricsContext verify the metrics key, sent as bearer token,
+// an empty server.metrics_key never matches
+func metricsContext(store *urlstore.Store) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := store.Config.Server.MetricsKey
+			token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if len(key) == 0 || subtle.ConstantTimeCompare([]byte(token), []byte(key)) == 0 {
+				http.Error(w, http.StatusText(403), 403)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
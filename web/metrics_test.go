@@ -0,0 +1,73 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/noandrea/distill/urlstore"
+)
+
+func TestMetricsDisabled(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+	router := RegisterEndpoints(store)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+	store.Config.Server.MetricsKey = "metrics"
+	id, _ := store.UpsertURLSimple(&urlstore.URLReq{URL: "https://ex.com/a", MaxRequests: 1})
+	router := RegisterEndpoints(store)
+	for _, path := range []string{"/" + id, "/" + id, "/notfound"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	tests := []struct {
+		name       string
+		token      string
+		wantStatus int
+	}{
+		{"no token", "", http.StatusForbidden},
+		{"wrong token", "Bearer test", http.StatusForbidden},
+		{"token", "Bearer metrics", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/metrics", nil)
+			if len(tt.token) > 0 {
+				req.Header.Set("Authorization", tt.token)
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tt.wantStatus)
+			}
+			if rr.Code != http.StatusOK {
+				return
+			}
+			body := rr.Body.String()
+			for _, want := range []string{
+				`distill_redirects_total{outcome="ok"} 1`,
+				`distill_redirects_total{outcome="exhausted"} 1`,
+				`distill_redirects_total{outcome="not_found"} 1`,
+				`distill_upserts_total 1`,
+				`distill_url_cache_hits_total 1`,
+				`distill_url_cache_misses_total 2`,
+				`distill_db_lsm_size_bytes`,
+				`distill_http_request_duration_seconds_count{code="302",method="GET",route="/{ID}"} 1`,
+			} {
+				if !strings.Contains(body, want) {
+					t.Errorf("metrics missing %v", want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package web
+
+import (
+	"html/template"
+	"net/http"
+
+	"github.com/jbrodriguez/mlog"
+	"github.com/noandrea/distill/urlstore"
+)
+
+// maximum size of the password form
+const passwordFormMaxBytes = 4096
+
+// passwordForm is the page asking the password of a protected url,
+// the form is posted to the same url so the path and the query are kept
+var passwordForm = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Password required</title>
+</head>
+<body>
+<form method="post">
+<p>This link is protected by a password.</p>
+{{if .}}<p role="alert">{{.}}</p>{{end}}
+<input type="password" name="password" autocomplete="current-password" autofocus required>
+<button type="submit">Continue</button>
+</form>
+</body>
+</html>
+`))
+
+// passwordMessages are the messages and the http status of the password form
+var passwordMessages = map[error]struct {
+	message string
+	status  int
+}{
+	urlstore.ErrPasswordRequired: {"", http.StatusOK},
+	urlstore.ErrWrongPassword:    {"Wrong password.", http.StatusUnauthorized},
+	urlstore.ErrTooManyAttempts:  {"Too many attempts, try again later.", http.StatusTooManyRequests},
+}
+
+// formPassword returns the password posted with the password form
+func formPassword(w http.ResponseWriter, r *http.Request) string {
+	if r.Method != http.MethodPost {
+		return ""
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, passwordFormMaxBytes)
+	return r.PostFormValue("password")
+}
+
+// renderPasswordForm writes the password form if the error is
+// a password error, it returns false otherwise
+func renderPasswordForm(w http.ResponseWriter, err error) bool {
+	m, ok := passwordMessages[err]
+	if !ok {
+		return false
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(m.status)
+	if err := passwordForm.Execute(w, m.message); err != nil {
+		mlog.Warning("Error rendering the password form: %v", err)
+	}
+	return true
+}